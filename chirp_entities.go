@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/m/internal/database"
+	"example.com/m/internal/entities"
+	"github.com/google/uuid"
+)
+
+type ChirpEntities struct {
+	Hashtags []string       `json:"hashtags"`
+	Mentions []ChirpMention `json:"mentions"`
+}
+
+// ChirpMention is an address mentioned in a chirp, lowercased and stripped of
+// trailing punctuation the way entities.Parse normalises it. It says nothing about whether the address belongs to an account, so chirps
+// can't be used to find out who is registered.
+type ChirpMention struct {
+	Email string `json:"email"`
+}
+
+// saveChirpEntities replaces the stored hashtags and mentions of a chirp with
+// the ones found in body. Mentions of emails that don't belong to a user are
+// dropped. Hashtags the chirp didn't carry before count as a new use for
+// trending; ones it keeps hold on to their original time, so deleting the
+// chirp later takes the use back out of the right bucket. Pass a transaction-bound q so the chirp and its entities commit
+// together.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	parsed := entities.Parse(body)
+
+	previousRows, err := q.ClearChirpHashtags(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+	previous := make(map[uuid.UUID]time.Time, len(previousRows))
+	for _, row := range previousRows {
+		previous[row.HashtagID] = row.CreatedAt
+	}
+
+	for _, tag := range parsed.Hashtags {
+		hashtag, err := q.UpsertHashtag(ctx, tag)
+		if err != nil {
+			return err
+		}
+		createdAt, seen := previous[hashtag.ID]
+		err = q.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID:   chirpID,
+			HashtagID: hashtag.ID,
+			CreatedAt: sql.NullTime{Time: createdAt, Valid: seen},
+		})
+		if err != nil {
+			return err
+		}
+		if !seen {
+			if err := q.IncrementHashtagUsage(ctx, hashtag.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := q.ClearChirpMentions(ctx, chirpID); err != nil {
+		return err
+	}
+	if len(parsed.Mentions) == 0 {
+		return nil
+	}
+	mentioned, err := q.GetUserIDsByEmails(ctx, parsed.Mentions)
+	if err != nil {
+		return err
+	}
+	for _, userID := range mentioned {
+		err = q.AddMention(ctx, database.AddMentionParams{
+			ChirpID: chirpID,
+			UserID:  userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadChirpEntities attaches stored hashtags to every chirp with one query.
+// Mentions are read back from the body rather than from the stored mentions,
+// which only feed the mentioned user's own list.
+func (cfg *apiConfig) loadChirpEntities(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	tagRows, err := cfg.db.GetHashtagsForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	tags := map[uuid.UUID][]string{}
+	for _, row := range tagRows {
+		tags[row.ChirpID] = append(tags[row.ChirpID], row.Tag)
+	}
+
+	for _, chirp := range chirps {
+		chirp.Entities = ChirpEntities{Hashtags: []string{}, Mentions: []ChirpMention{}}
+		chirp.Entities.Hashtags = append(chirp.Entities.Hashtags, tags[chirp.ID]...)
+		for _, email := range entities.Parse(chirp.Body).Mentions {
+			chirp.Entities.Mentions = append(chirp.Entities.Mentions, ChirpMention{Email: email})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Command bootstrap-admin promotes the first admin. Once an admin exists,
+// further role changes go through PUT /admin/users/{userID}/role.
+//
+//	go run ./cmd/bootstrap-admin -email admin@example.com
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to make admin")
+	flag.Parse()
+	if *email == "" {
+		log.Fatal("-email must be set")
+	}
+
+	godotenv.Load()
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		log.Fatal("DB_URL must be set")
+	}
+
+	dbConn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Error opening database: %s", err)
+	}
+	defer dbConn.Close()
+
+	ctx := context.Background()
+	// With no admin rows to lock, only serializable isolation stops two runs
+	// from both finding none and each promoting someone.
+	tx, err := dbConn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		log.Fatalf("Error starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+	qtx := database.New(tx)
+
+	admins, err := qtx.LockAdmins(ctx)
+	if err != nil {
+		log.Fatalf("Error checking for admins: %s", err)
+	}
+	if len(admins) > 0 {
+		log.Fatal("An admin already exists; use PUT /admin/users/{userID}/role instead")
+	}
+
+	user, err := qtx.GetUserByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("Error finding user %s: %s", *email, err)
+	}
+
+	_, err = qtx.UpdateUserRole(ctx, database.UpdateUserRoleParams{
+		ID:   user.ID,
+		Role: auth.RoleAdmin,
+	})
+	if err != nil {
+		log.Fatalf("Error updating role: %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error committing: %s", err)
+	}
+	log.Printf("%s is now an admin", *email)
+}
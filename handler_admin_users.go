@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerSetUserRole changes a user's role. Their existing access tokens are
+// revoked so the old role claim stops working straight away.
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+	type response struct {
+		ID   uuid.UUID `json:"id"`
+		Role string    `json:"role"`
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+	if !auth.ValidRole(params.Role) {
+		respondWithError(w, http.StatusBadRequest, "Role must be one of user, moderator or admin", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user that has the given ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+
+	// Demoting the only admin would leave nobody able to manage roles short
+	// of running the bootstrap command again. The admin rows stay locked
+	// until commit, so two admins can't demote each other at once.
+	if user.Role == auth.RoleAdmin && params.Role != auth.RoleAdmin {
+		admins, err := qtx.LockAdmins(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+			return
+		}
+		if len(admins) <= 1 {
+			respondWithError(w, http.StatusConflict, "Can't demote the last admin", nil)
+			return
+		}
+	}
+
+	_, err = qtx.UpdateUserRole(r.Context(), database.UpdateUserRoleParams{
+		ID:   userID,
+		Role: params.Role,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+	cfg.authUsers.Forget(userID)
+
+	respondWithJSON(w, http.StatusOK, response{
+		ID:   userID,
+		Role: params.Role,
+	})
+}
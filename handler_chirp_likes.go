@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, userID, ok := cfg.parseLikeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't like chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, userID, ok := cfg.parseLikeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unlike chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) parseLikeRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	_, err = cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return chirpID, idFromToken, true
+}
+
+// optionalViewer returns the caller's user ID on routes wrapped with
+// auth.Optional. Public endpoints use it to personalise responses; without a
+// valid access token the viewer is anonymous.
+func (cfg *apiConfig) optionalViewer(r *http.Request) uuid.NullUUID {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: user.ID, Valid: true}
+}
+
+// markLikedByViewer fills in LikedByMe for every chirp with a single query,
+// so list endpoints don't issue one lookup per row.
+func (cfg *apiConfig) markLikedByViewer(ctx context.Context, viewer uuid.NullUUID, chirps []*Chirp) error {
+	if !viewer.Valid || len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	likedIDs, err := cfg.db.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+		UserID:   viewer.UUID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]struct{}, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = struct{}{}
+	}
+	for _, chirp := range chirps {
+		_, chirp.LikedByMe = liked[chirp.ID]
+	}
+	return nil
+}
+
+func (p *chirpPage) refs() []*Chirp {
+	refs := make([]*Chirp, 0, len(p.Chirps))
+	for i := range p.Chirps {
+		refs = append(refs, &p.Chirps[i])
+	}
+	return refs
+}
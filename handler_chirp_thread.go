@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxThreadAncestors      = 50
+	defaultThreadReplyDepth = 3
+	maxThreadReplyDepth     = 10
+	maxThreadReplies        = 500
+)
+
+type ChirpThreadNode struct {
+	Chirp
+	Replies []ChirpThreadNode `json:"replies"`
+}
+
+// chirpTombstone is all a deleted chirp shows of itself in a thread: enough
+// to attach its replies, and nothing that would pass for a real value.
+type chirpTombstone struct {
+	ID        uuid.UUID  `json:"id"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	Deleted   bool       `json:"deleted"`
+}
+
+// plainChirp is Chirp without its MarshalJSON, for encoding live chirps.
+type plainChirp Chirp
+
+// MarshalJSON encodes a tombstone as chirpTombstone and anything else as is.
+func (c Chirp) MarshalJSON() ([]byte, error) {
+	if c.Deleted {
+		return json.Marshal(chirpTombstone{ID: c.ID, InReplyTo: c.InReplyTo, Deleted: true})
+	}
+	return json.Marshal(plainChirp(c))
+}
+
+// MarshalJSON is needed because the embedded Chirp's would otherwise encode
+// the node without its replies.
+func (n ChirpThreadNode) MarshalJSON() ([]byte, error) {
+	if n.Deleted {
+		return json.Marshal(struct {
+			chirpTombstone
+			Replies []ChirpThreadNode `json:"replies"`
+		}{chirpTombstone{ID: n.ID, InReplyTo: n.InReplyTo, Deleted: true}, n.Replies})
+	}
+	return json.Marshal(struct {
+		plainChirp
+		Replies []ChirpThreadNode `json:"replies"`
+	}{plainChirp(n.Chirp), n.Replies})
+}
+
+// threadChirp is chirpFromDB for chirps shown in a thread. A deleted chirp
+// with replies still holds its place as a tombstone, keeping only what's
+// needed to attach its replies.
+func threadChirp(dbChirp database.Chirp) Chirp {
+	if !dbChirp.DeletedAt.Valid {
+		return chirpFromDB(dbChirp)
+	}
+	chirp := Chirp{ID: dbChirp.ID, Deleted: true}
+	if dbChirp.InReplyTo.Valid {
+		chirp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	return chirp
+}
+
+func (cfg *apiConfig) handlerGetChirpThread(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Ancestors []Chirp         `json:"ancestors"`
+		Chirp     ChirpThreadNode `json:"chirp"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	depth := defaultThreadReplyDepth
+	if rawDepth := r.URL.Query().Get("depth"); rawDepth != "" {
+		depth, err = strconv.Atoi(rawDepth)
+		if err != nil || depth < 0 {
+			respondWithError(w, http.StatusBadRequest, "depth must be a non-negative integer", err)
+			return
+		}
+		depth = min(depth, maxThreadReplyDepth)
+	}
+
+	root, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err)
+		return
+	}
+
+	ancestorRows, err := cfg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ChirpID:  root.ID,
+		MaxDepth: maxThreadAncestors,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get thread ancestors", err)
+		return
+	}
+
+	ancestors := []Chirp{}
+	for _, row := range ancestorRows {
+		ancestors = append(ancestors, threadChirp(row.Chirp))
+	}
+
+	descendantRows := []database.GetChirpDescendantsRow{}
+	if depth > 0 {
+		descendantRows, err = cfg.db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+			ChirpID:    root.ID,
+			MaxDepth:   int32(depth),
+			MaxReplies: maxThreadReplies,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get thread replies", err)
+			return
+		}
+	}
+
+	resp := response{
+		Ancestors: ancestors,
+		Chirp:     buildReplyTree(root, descendantRows),
+	}
+
+	refs := []*Chirp{}
+	for i := range resp.Ancestors {
+		if !resp.Ancestors[i].Deleted {
+			refs = append(refs, &resp.Ancestors[i])
+		}
+	}
+	refs = resp.Chirp.collectRefs(refs)
+	err = cfg.hydrateChirps(r.Context(), cfg.optionalViewer(r), refs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (n *ChirpThreadNode) collectRefs(refs []*Chirp) []*Chirp {
+	if !n.Deleted {
+		refs = append(refs, &n.Chirp)
+	}
+	for i := range n.Replies {
+		refs = n.Replies[i].collectRefs(refs)
+	}
+	return refs
+}
+
+// buildReplyTree nests the flat descendant rows under their parents. Rows
+// arrive ordered by depth, then creation time, so replies keep that order.
+// Deleted replies are kept as tombstones only when something below them is
+// still live.
+func buildReplyTree(root database.Chirp, rows []database.GetChirpDescendantsRow) ChirpThreadNode {
+	children := map[uuid.UUID][]database.Chirp{}
+	for _, row := range rows {
+		parentID := row.Chirp.InReplyTo.UUID
+		children[parentID] = append(children[parentID], row.Chirp)
+	}
+
+	var build func(dbChirp database.Chirp) ChirpThreadNode
+	build = func(dbChirp database.Chirp) ChirpThreadNode {
+		node := ChirpThreadNode{Chirp: threadChirp(dbChirp), Replies: []ChirpThreadNode{}}
+		for _, child := range children[dbChirp.ID] {
+			reply := build(child)
+			if reply.Deleted && len(reply.Replies) == 0 {
+				continue
+			}
+			node.Replies = append(node.Replies, reply)
+		}
+		return node
+	}
+	return build(root)
+}
@@ -1,29 +1,101 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
-	"strings"
 	"time"
 
 	"example.com/m/internal/auth"
 	"example.com/m/internal/database"
+	"example.com/m/internal/moderation"
+	"example.com/m/internal/textlen"
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UserID    uuid.UUID `json:"user_id"`
-	Body      string    `json:"body"`
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Body      string        `json:"body"`
+	InReplyTo *uuid.UUID    `json:"in_reply_to,omitempty"`
+	LikeCount int32         `json:"like_count"`
+	LikedByMe bool          `json:"liked_by_me"`
+	RechirpOf *uuid.UUID    `json:"rechirp_of,omitempty"`
+	Original  *Chirp        `json:"original,omitempty"`
+	Entities  ChirpEntities `json:"entities"`
+	// Deleted marks a tombstone standing in for a deleted chirp in a thread.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		LikeCount: dbChirp.LikeCount,
+		Entities:  ChirpEntities{Hashtags: []string{}, Mentions: []ChirpMention{}},
+	}
+	if dbChirp.InReplyTo.Valid {
+		chirp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	if dbChirp.RechirpOf.Valid {
+		chirp.RechirpOf = &dbChirp.RechirpOf.UUID
+	}
+	return chirp
+}
+
+// hydrateChirps fills in the parts of a Chirp that don't live on its own row:
+// the original embedded in a rechirp, hashtags and mentions, and whether the
+// viewer liked each chirp. Each part costs one batched query regardless of how
+// many chirps are passed.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewer uuid.NullUUID, chirps []*Chirp) error {
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			originalIDs = append(originalIDs, *chirp.RechirpOf)
+		}
+	}
+
+	withOriginals := append([]*Chirp{}, chirps...)
+	if len(originalIDs) > 0 {
+		dbOriginals, err := cfg.db.GetChirpsByIDs(ctx, originalIDs)
+		if err != nil {
+			return err
+		}
+		originals := make(map[uuid.UUID]database.Chirp, len(dbOriginals))
+		for _, dbOriginal := range dbOriginals {
+			originals[dbOriginal.ID] = dbOriginal
+		}
+
+		// Originals that have since been deleted are simply left out.
+		for _, chirp := range chirps {
+			if chirp.RechirpOf == nil {
+				continue
+			}
+			if dbOriginal, ok := originals[*chirp.RechirpOf]; ok {
+				original := chirpFromDB(dbOriginal)
+				chirp.Original = &original
+				withOriginals = append(withOriginals, chirp.Original)
+			}
+		}
+	}
+
+	if err := cfg.loadChirpEntities(ctx, withOriginals); err != nil {
+		return err
+	}
+	return cfg.markLikedByViewer(ctx, viewer, withOriginals)
 }
 
 func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -35,90 +107,112 @@ func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	bearer_token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Error obtaining sign-in token", err)
+	id_from_token := auth.MustUserFromContext(r.Context()).ID
+
+	tier := cfg.userTier(r.Context())
+
+	err = cfg.checkChirpRate(r.Context(), id_from_token, tier)
+	if errors.Is(err, errChirpRateLimited) {
+		respondWithError(w, http.StatusTooManyRequests, err.Error(), err)
 		return
 	}
-
-	id_from_token, err := auth.ValidateJWT(bearer_token, cfg.key)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Error validating sign-in token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check posting rate", err)
 		return
 	}
 
-	cleaned, err := validateChirp(params.Body)
-	log.Printf("Decoded cleaned: body=%q", cleaned)
+	moderated, err := cfg.validateChirp(params.Body, tier.MaxChirpLength)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: id_from_token,
-	})
+	inReplyTo := uuid.NullUUID{}
+	if params.InReplyTo != nil {
+		parent, err := cfg.db.GetChirpByID(r.Context(), *params.InReplyTo)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find the chirp being replied to", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get the chirp being replied to", err)
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
 
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
+	chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		Body:      moderated.Body,
 		UserID:    id_from_token,
+		InReplyTo: inReplyTo,
 	})
-}
 
-func validateChirp(body string) (string, error) {
-	const maxChirpLength = 140
-	if len(body) > maxChirpLength {
-		return "", errors.New("Chirp is too long")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
+		return
 	}
 
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	err = saveChirpEntities(r.Context(), qtx, chirp.ID, chirp.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save chirp hashtags and mentions", err)
+		return
 	}
-	cleaned := getCleanedBody(body, badWords)
-	return cleaned, nil
-}
 
-func getCleanedBody(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		loweredWord := strings.ToLower(word)
-		if _, ok := badWords[loweredWord]; ok {
-			words[i] = "****"
-		}
+	err = recordModerationFlags(r.Context(), qtx, chirp.ID, moderated)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't flag chirp for review", err)
+		return
 	}
-	cleaned := strings.Join(words, " ")
-	return cleaned
-}
 
-func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request) {
-	idChirpToFind, err1 := uuid.Parse(r.PathValue("chirpID"))
-	if err1 != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode url parameters to string for internal use", err1)
+	err = cfg.enqueueWebhook(r.Context(), qtx, chirp.UserID, webhookChirpCreated, chirpFromDB(chirp))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't queue chirp webhooks", err)
 		return
 	}
 
-	bearerToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Error obtaining sign-in token", err)
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
 		return
 	}
 
-	idFromToken, err := auth.ValidateJWT(bearerToken, cfg.key)
+	created := chirpFromDB(chirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: id_from_token, Valid: true}, []*Chirp{&created})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Error validating sign-in token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// validateChirp checks body against the author's length limit and runs it
+// through moderation. Length is measured with textlen.Weighted, so it counts
+// characters as users see them rather than bytes.
+func (cfg *apiConfig) validateChirp(body string, maxLength int) (moderation.Result, error) {
+	if textlen.Weighted(body) > maxLength {
+		return moderation.Result{}, errors.New("Chirp is too long")
+	}
+
+	return cfg.moderation.Moderate(body)
+}
+
+func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request) {
+	idChirpToFind, err1 := uuid.Parse(r.PathValue("chirpID"))
+	if err1 != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode url parameters to string for internal use", err1)
 		return
 	}
 
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
 	chirpByID, err2 := cfg.db.GetChirpByID(r.Context(), idChirpToFind)
 	if err2 != nil {
 		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err2)
@@ -130,11 +224,51 @@ func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err3 := cfg.db.DeleteChirpWithID(r.Context(), chirpByID.ID)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err3 := qtx.SoftDeleteChirpWithID(r.Context(), chirpByID.ID)
 	if err3 != nil {
 		respondWithError(w, http.StatusNotFound, "Couldn't find chirp with parsed ID for deletion", err3)
 		return
 	}
 
+	// A deleted chirp stops counting towards trending; a restore adds it back.
+	err = qtx.AdjustChirpHashtagUsage(r.Context(), database.AdjustChirpHashtagUsageParams{
+		Delta:   -1,
+		ChirpID: chirpByID.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update hashtag usage", err)
+		return
+	}
+
+	// Plain rechirps have nothing to show without their original, so they go
+	// with it. They share its deleted_at, which lets a restore bring them back.
+	err = qtx.SoftDeletePlainRechirpsOf(r.Context(), uuid.NullUUID{UUID: chirpByID.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete rechirps of chirp", err)
+		return
+	}
+
+	err = cfg.enqueueWebhook(r.Context(), qtx, chirpByID.UserID, webhookChirpDeleted, chirpDeletedEvent{
+		ID:     chirpByID.ID,
+		UserID: chirpByID.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't queue chirp webhooks", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Body      string    `json:"body"`
+}
+
+func (cfg *apiConfig) handlerChirpsUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	tier := cfg.userTier(r.Context())
+
+	if !tier.CanEditChirps {
+		respondWithError(w, http.StatusForbidden, "Editing chirps requires a Chirpy Red membership", nil)
+		return
+	}
+
+	moderated, err := cfg.validateChirp(params.Body, tier.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Lock the row so two concurrent edits can't both record the same prior body.
+	current, err := qtx.GetChirpByIDForUpdate(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	if current.UserID != idFromToken {
+		respondWithError(w, http.StatusForbidden, "You are not allowed to perform modifications to this chirp", nil)
+		return
+	}
+
+	if current.RechirpOf.Valid && current.Body == "" {
+		respondWithError(w, http.StatusBadRequest, "Plain rechirps cannot be edited", nil)
+		return
+	}
+
+	_, err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+		ChirpID: current.ID,
+		Body:    current.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record chirp revision", err)
+		return
+	}
+
+	updated, err := qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:   current.ID,
+		Body: moderated.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	err = saveChirpEntities(r.Context(), qtx, updated.ID, updated.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save chirp hashtags and mentions", err)
+		return
+	}
+
+	err = recordModerationFlags(r.Context(), qtx, updated.ID, moderated)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't flag chirp for review", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	chirp := chirpFromDB(updated)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: idFromToken, Valid: true}, []*Chirp{&chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (cfg *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	_, err = cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err)
+		return
+	}
+
+	dbRevisions, err := cfg.db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp revisions", err)
+		return
+	}
+
+	revisions := []ChirpRevision{}
+	for _, dbRevision := range dbRevisions {
+		revisions = append(revisions, ChirpRevision{
+			ID:        dbRevision.ID,
+			CreatedAt: dbRevision.CreatedAt,
+			ChirpID:   dbRevision.ChirpID,
+			Body:      dbRevision.Body,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, revisions)
+}
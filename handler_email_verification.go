@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"example.com/m/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMailFrom = "Chirpy <no-reply@localhost>"
+	// emailVerificationTTL is how long a verification link stays usable.
+	emailVerificationTTL = 24 * time.Hour
+	// emailVerificationResendInterval is the least time between two
+	// verification emails to the same account.
+	emailVerificationResendInterval = time.Minute
+	// backgroundMailTimeout bounds mail work done after the response.
+	backgroundMailTimeout = 30 * time.Second
+)
+
+// loadMailer sends through the SMTP server at SMTP_ADDR when it is set.
+// Otherwise mail is appended to MAIL_FILE, or written to the server log, so
+// accounts can still be verified during local development.
+func loadMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultMailFrom
+	}
+
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		return mailer.NewSMTPMailer(addr, from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	}
+	if path := os.Getenv("MAIL_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalf("Error opening mail file: %s", err)
+		}
+		return mailer.NewWriterMailer(f, from)
+	}
+	return mailer.NewWriterMailer(log.Writer(), from)
+}
+
+// createEmailVerification records a new verification for userID with q and
+// returns the signed token to mail out.
+func (cfg *apiConfig) createEmailVerification(ctx context.Context, q *database.Queries, userID uuid.UUID) (string, error) {
+	verification, err := q.CreateEmailVerification(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return auth.MakeEmailVerificationToken(userID, verification.ID, cfg.key, emailVerificationTTL)
+}
+
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, email, token string) error {
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy account",
+		Body: fmt.Sprintf("Welcome to Chirpy!\n\n"+
+			"To finish signing up, verify your email address with this token:\n\n%s\n\n"+
+			"It expires in %v. If you didn't sign up for Chirpy, you can ignore this email.\n",
+			token, emailVerificationTTL),
+	})
+}
+
+// sendInBackground runs fn after the handler returns, with a context of its
+// own so it isn't cancelled along with the request. Errors are only logged.
+func sendInBackground(what string, fn func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundMailTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			log.Printf("Error sending %s: %s", what, err)
+		}
+	}()
+}
+
+// handlerVerifyEmail activates the account a verification token was mailed
+// to. Every outstanding token for the account is used up with it.
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	userID, tokenID, err := auth.ValidateEmailVerificationToken(params.Token, cfg.key)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Verification token is invalid or expired", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	verification, err := qtx.GetEmailVerificationForUpdate(r.Context(), tokenID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (verification.UserID != userID || verification.UsedAt.Valid)) {
+		respondWithError(w, http.StatusBadRequest, "Verification token is invalid or expired", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+
+	if err := qtx.UseEmailVerifications(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+	if err := qtx.VerifyUserEmail(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerResendVerification mails a fresh verification token. The lookup and
+// the mail happen after the response, which is always 204, and requests
+// inside the resend interval are dropped, so the endpoint can't be used to
+// probe for accounts or to flood someone's inbox.
+func (cfg *apiConfig) handlerResendVerification(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	sendInBackground("verification email", func(ctx context.Context) error {
+		return cfg.resendVerification(ctx, params.Email)
+	})
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// resendVerification mails a new token to email if it belongs to an account
+// still waiting to be verified. The account row stays locked while the resend
+// interval is checked, so concurrent requests can't both get through.
+func (cfg *apiConfig) resendVerification(ctx context.Context, email string) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.GetUserByEmailForUpdate(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.EmailVerifiedAt.Valid) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recent, err := qtx.CountEmailVerificationsSince(ctx, database.CountEmailVerificationsSinceParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-emailVerificationResendInterval),
+	})
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return nil
+	}
+
+	token, err := cfg.createEmailVerification(ctx, qtx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return cfg.sendVerificationEmail(ctx, user.Email, token)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"example.com/m/internal/entities"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerGetTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag", nil)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorArgs()
+	var chirpsArray []database.Chirp
+	if page.desc {
+		chirpsArray, err = cfg.db.GetTagChirpsPageDesc(r.Context(), database.GetTagChirpsPageDescParams{
+			Tag:             tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	} else {
+		chirpsArray, err = cfg.db.GetTagChirpsPageAsc(r.Context(), database.GetTagChirpsPageAscParams{
+			Tag:             tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps for hashtag", err)
+		return
+	}
+
+	resp := newChirpPage(chirpsArray, page)
+	err = cfg.hydrateChirps(r.Context(), cfg.optionalViewer(r), resp.refs())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerGetMyMentions(w http.ResponseWriter, r *http.Request) {
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorArgs()
+	var chirpsArray []database.Chirp
+	if page.desc {
+		chirpsArray, err = cfg.db.GetMentionChirpsPageDesc(r.Context(), database.GetMentionChirpsPageDescParams{
+			UserID:          idFromToken,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	} else {
+		chirpsArray, err = cfg.db.GetMentionChirpsPageAsc(r.Context(), database.GetMentionChirpsPageAscParams{
+			UserID:          idFromToken,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get mentions", err)
+		return
+	}
+
+	resp := newChirpPage(chirpsArray, page)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: idFromToken, Valid: true}, resp.refs())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
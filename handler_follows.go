@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	if followeeID == idFromToken {
+		respondWithError(w, http.StatusBadRequest, "You cannot follow yourself", nil)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user with provided id", err)
+		return
+	}
+
+	err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: idFromToken,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: idFromToken,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, page, ok := parseFollowListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorArgs()
+	var follows []database.Follow
+	var err error
+	if page.desc {
+		follows, err = cfg.db.GetFollowersPageDesc(r.Context(), database.GetFollowersPageDescParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	} else {
+		follows, err = cfg.db.GetFollowersPageAsc(r.Context(), database.GetFollowersPageAscParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get followers", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newFollowPage(follows, page, func(f database.Follow) uuid.UUID {
+		return f.FollowerID
+	}))
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, page, ok := parseFollowListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorArgs()
+	var follows []database.Follow
+	var err error
+	if page.desc {
+		follows, err = cfg.db.GetFollowingPageDesc(r.Context(), database.GetFollowingPageDescParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	} else {
+		follows, err = cfg.db.GetFollowingPageAsc(r.Context(), database.GetFollowingPageAscParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get followed users", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newFollowPage(follows, page, func(f database.Follow) uuid.UUID {
+		return f.FolloweeID
+	}))
+}
+
+func parseFollowListRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, pageParams, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return uuid.Nil, pageParams{}, false
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return uuid.Nil, pageParams{}, false
+	}
+	return userID, page, true
+}
+
+// newFollowPage lists the other side of each follow edge in the order the
+// list query returned them, oldest first unless sort=desc. The cursor is keyed
+// on (followed_at, user_id) to match the list queries.
+func newFollowPage(follows []database.Follow, p pageParams, otherUser func(database.Follow) uuid.UUID) followPage {
+	page := followPage{Users: []Follow{}}
+	follows, hasMore := splitPage(follows, p)
+	for _, follow := range follows {
+		page.Users = append(page.Users, Follow{
+			UserID:     otherUser(follow),
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+
+	if hasMore {
+		last := page.Users[len(page.Users)-1]
+		page.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.FollowedAt, ID: last.UserID})
+	}
+	return page
+}
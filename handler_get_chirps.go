@@ -2,66 +2,54 @@ package main
 
 import (
 	"net/http"
-	"sort"
 
+	"example.com/m/internal/database"
 	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
-	author := r.URL.Query().Get("author_id")
-	if len(author) > 0 {
-		authorID, err := uuid.Parse(author)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Couldn't parsed provided query parameter as ID", err)
-			return
-		}
-
-		authorChirps, err := cfg.db.GetChirpsByUserID(r.Context(), authorID)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Couldn't find chirps created by given user ID", err)
-			return
-		}
-
-		returnChirps := []Chirp{}
-
-		// Loop through database chirps array returned
-		for _, dbChirp := range authorChirps {
-			returnChirps = append(returnChirps, Chirp{
-				ID:        dbChirp.ID,
-				CreatedAt: dbChirp.CreatedAt,
-				UpdatedAt: dbChirp.UpdatedAt,
-				Body:      dbChirp.Body,
-				UserID:    dbChirp.UserID,
-			})
-		}
-		respondWithJSON(w, http.StatusOK, returnChirps)
+	page, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	chirpsArray, err := cfg.db.GetChirps(r.Context())
+	authorID, err := parseAuthorFilter(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't get all chirps", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't parsed provided query parameter as ID", err)
 		return
 	}
-	returnChirps := []Chirp{}
 
-	// Loop through database chirps array returned
-	for _, dbChirp := range chirpsArray {
-		returnChirps = append(returnChirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
+	cursorCreatedAt, cursorID := page.cursorArgs()
+	var chirpsArray []database.Chirp
+	if page.desc {
+		chirpsArray, err = cfg.db.GetChirpsPageDesc(r.Context(), database.GetChirpsPageDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	} else {
+		chirpsArray, err = cfg.db.GetChirpsPageAsc(r.Context(), database.GetChirpsPageAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
 		})
 	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+		return
+	}
 
-	order := r.URL.Query().Get("sort")
-	if order == "desc" {
-		sort.Slice(returnChirps, func(i, j int) bool { return returnChirps[i].CreatedAt.After(returnChirps[j].CreatedAt) })
+	resp := newChirpPage(chirpsArray, page)
+	err = cfg.hydrateChirps(r.Context(), cfg.optionalViewer(r), resp.refs())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, returnChirps)
+	respondWithJSON(w, http.StatusOK, resp)
 }
 
 func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, r *http.Request) {
@@ -75,11 +63,11 @@ func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, r *http.Request
 		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err2)
 		return
 	}
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        chirpByID.ID,
-		CreatedAt: chirpByID.CreatedAt,
-		UpdatedAt: chirpByID.UpdatedAt,
-		Body:      chirpByID.Body,
-		UserID:    chirpByID.UserID,
-	})
+	chirp := chirpFromDB(chirpByID)
+	err := cfg.hydrateChirps(r.Context(), cfg.optionalViewer(r), []*Chirp{&chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirp)
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"example.com/m/internal/auth"
+)
+
+// loadSigningKeys builds the JWT keyring. With JWT_KEYS_DIR set, tokens are
+// signed by the JWT_ACTIVE_KID key from that directory and SECRET, if still
+// set, only verifies tokens issued before the switch. Otherwise SECRET signs
+// HS256 tokens as before.
+func loadSigningKeys() *auth.Keyring {
+	secret := os.Getenv("SECRET")
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if keysDir == "" {
+		if secret == "" {
+			log.Fatal("SECRET or JWT_KEYS_DIR must be set")
+		}
+		return auth.NewHMACKeyring(secret)
+	}
+
+	keys, err := auth.LoadKeyring(keysDir, os.Getenv("JWT_ACTIVE_KID"), secret)
+	if err != nil {
+		log.Fatalf("Error loading JWT signing keys: %s", err)
+	}
+	return keys
+}
+
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.key.JWKS())
+}
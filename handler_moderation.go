@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"example.com/m/internal/database"
+	"example.com/m/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const moderationFlagsLimit = 100
+
+type ModerationWord struct {
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ModerationFlag struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Filter    string    `json:"filter"`
+	Term      string    `json:"term"`
+}
+
+func (cfg *apiConfig) handlerGetModerationWords(w http.ResponseWriter, r *http.Request) {
+	dbWords, err := cfg.db.GetModerationWords(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get moderation words", err)
+		return
+	}
+
+	words := []ModerationWord{}
+	for _, dbWord := range dbWords {
+		words = append(words, moderationWordFromDB(dbWord))
+	}
+	respondWithJSON(w, http.StatusOK, words)
+}
+
+func (cfg *apiConfig) handlerPutModerationWord(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Word   string `json:"word"`
+		Action string `json:"action"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	word := strings.ToLower(strings.TrimSpace(params.Word))
+	if word == "" || strings.ContainsFunc(word, unicode.IsSpace) {
+		respondWithError(w, http.StatusBadRequest, "word must be a single non-empty word", nil)
+		return
+	}
+	action, err := moderation.ParseAction(params.Action)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbWord, err := cfg.db.UpsertModerationWord(r.Context(), database.UpsertModerationWordParams{
+		Word:   word,
+		Action: string(action),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save moderation word", err)
+		return
+	}
+
+	err = cfg.reloadModerationWords(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation words", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, moderationWordFromDB(dbWord))
+}
+
+func (cfg *apiConfig) handlerDeleteModerationWord(w http.ResponseWriter, r *http.Request) {
+	word := strings.ToLower(r.PathValue("word"))
+
+	deleted, err := cfg.db.DeleteModerationWord(r.Context(), word)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete moderation word", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find moderation word", nil)
+		return
+	}
+
+	err = cfg.reloadModerationWords(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation words", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) handlerGetModerationFlags(w http.ResponseWriter, r *http.Request) {
+	dbFlags, err := cfg.db.GetModerationFlags(r.Context(), moderationFlagsLimit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get moderation flags", err)
+		return
+	}
+
+	flags := []ModerationFlag{}
+	for _, dbFlag := range dbFlags {
+		flags = append(flags, ModerationFlag{
+			ID:        dbFlag.ID,
+			CreatedAt: dbFlag.CreatedAt,
+			ChirpID:   dbFlag.ChirpID,
+			Filter:    dbFlag.Filter,
+			Term:      dbFlag.Term,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, flags)
+}
+
+func moderationWordFromDB(dbWord database.ModerationWord) ModerationWord {
+	return ModerationWord{
+		Word:      dbWord.Word,
+		Action:    dbWord.Action,
+		CreatedAt: dbWord.CreatedAt,
+		UpdatedAt: dbWord.UpdatedAt,
+	}
+}
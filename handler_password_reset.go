@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"example.com/m/internal/mailer"
+)
+
+// passwordResetInterval is the least time between two reset emails to the
+// same account. Reset tokens themselves expire an hour after they're issued.
+const passwordResetInterval = time.Minute
+
+// handlerForgotPassword mails a one-time reset token. The lookup and the mail
+// happen after the response, which is always 204, so neither its content nor
+// its timing says which addresses are registered.
+func (cfg *apiConfig) handlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	sendInBackground("password reset", func(ctx context.Context) error {
+		return cfg.sendPasswordReset(ctx, params.Email)
+	})
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recent, err := cfg.db.CountPasswordResetsSince(ctx, database.CountPasswordResetsSinceParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-passwordResetInterval),
+	})
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return nil
+	}
+
+	token, err := auth.MakePasswordResetToken()
+	if err != nil {
+		return err
+	}
+	_, err = cfg.db.CreatePasswordReset(ctx, database.CreatePasswordResetParams{
+		TokenHash: auth.HashPasswordResetToken(token),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"To choose a new password, use this token:\n\n%s\n\n"+
+			"It expires in an hour and can only be used once. If you didn't ask for this, you can ignore this email.\n",
+			token),
+	})
+}
+
+// handlerResetPassword sets a new password with a mailed reset token and signs
+// the account out everywhere.
+func (cfg *apiConfig) handlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Password can't be empty", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	reset, err := qtx.GetPasswordResetForUpdate(r.Context(), auth.HashPasswordResetToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (reset.UsedAt.Valid || !reset.ExpiresAt.After(time.Now()))) {
+		respondWithError(w, http.StatusBadRequest, "Reset token is invalid or expired", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't encrypt password", err)
+		return
+	}
+
+	// Bumps the token version too, so access tokens die with the sessions.
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             reset.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+
+	if err := qtx.UsePasswordResets(r.Context(), reset.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+
+	if _, err := qtx.RevokeAllSessions(r.Context(), reset.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	cfg.authUsers.Forget(reset.UserID)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"example.com/m/internal/moderation"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// handlerRechirp re-shares a chirp. An empty or missing body makes a plain
+// rechirp; anything else is posted as a quote of the original.
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	// A request without a body, chunked or not, is a plain rechirp.
+	params := parameters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	original, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp that has the given ID", err)
+		return
+	}
+
+	// Rechirping a plain rechirp shares what it points at, so chains never form.
+	if original.RechirpOf.Valid && original.Body == "" {
+		original, err = cfg.db.GetChirpByID(r.Context(), original.RechirpOf.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Couldn't find the original chirp", err)
+			return
+		}
+	}
+
+	tier := cfg.userTier(r.Context())
+
+	err = cfg.checkChirpRate(r.Context(), idFromToken, tier)
+	if errors.Is(err, errChirpRateLimited) {
+		respondWithError(w, http.StatusTooManyRequests, err.Error(), err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check posting rate", err)
+		return
+	}
+
+	moderated := moderation.Result{}
+	if params.Body != "" {
+		moderated, err = cfg.validateChirp(params.Body, tier.MaxChirpLength)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create rechirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	rechirp, err := qtx.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		Body:      moderated.Body,
+		UserID:    idFromToken,
+		RechirpOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		respondWithError(w, http.StatusConflict, "You have already rechirped this chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create rechirp", err)
+		return
+	}
+
+	err = saveChirpEntities(r.Context(), qtx, rechirp.ID, rechirp.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save chirp hashtags and mentions", err)
+		return
+	}
+
+	err = recordModerationFlags(r.Context(), qtx, rechirp.ID, moderated)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't flag chirp for review", err)
+		return
+	}
+
+	err = cfg.enqueueWebhook(r.Context(), qtx, rechirp.UserID, webhookChirpCreated, chirpFromDB(rechirp))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't queue chirp webhooks", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create rechirp", err)
+		return
+	}
+
+	chirp := chirpFromDB(rechirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: idFromToken, Valid: true}, []*Chirp{&chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirp)
+}
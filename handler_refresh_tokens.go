@@ -1,15 +1,29 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
 )
 
+// authUserCacheTTL bounds how long another server may keep accepting access
+// tokens after a user revokes them, or acting on their old role or
+// membership.
+const authUserCacheTTL = 30 * time.Second
+
+// handlerRefreshJWT trades a refresh token for a new access token and a new
+// refresh token in the same family; the presented token is revoked. Seeing a
+// rotated token again means it was copied by someone, so every token in its
+// family is revoked and both parties have to sign in again.
 func (cfg *apiConfig) handlerRefreshJWT(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	grab_refresh_tkn, err := auth.GetBearerToken(r.Header)
@@ -18,20 +32,123 @@ func (cfg *apiConfig) handlerRefreshJWT(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user_with_rtoken, err := cfg.db.GetUserByRefreshToken(r.Context(), grab_refresh_tkn)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't refresh sign-in token", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	stored, err := qtx.GetRefreshTokenForUpdate(r.Context(), auth.HashRefreshToken(grab_refresh_tkn))
+	if errors.Is(err, sql.ErrNoRows) {
 		respondWithError(w, http.StatusUnauthorized, "No user with matching valid refresh token found", err)
 		return
 	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't refresh sign-in token", err)
+		return
+	}
+
+	if stored.RevokedAt.Valid {
+		// Only a token that was rotated has a newer token in its family. One
+		// revoked by signing out or ending the session was never handed on,
+		// so seeing it again is no sign of theft.
+		rotated, err := qtx.HasRefreshTokenSuccessor(r.Context(), database.HasRefreshTokenSuccessorParams{
+			FamilyID:  stored.FamilyID,
+			CreatedAt: stored.CreatedAt,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't refresh sign-in token", err)
+			return
+		}
+		if !rotated {
+			respondWithError(w, http.StatusUnauthorized, "Refresh token has been revoked", nil)
+			return
+		}
 
-	new_jwt, err := auth.MakeJWT(user_with_rtoken.ID, cfg.key, time.Duration((60*60)*time.Second))
+		revoked, err := qtx.RevokeRefreshTokenFamily(r.Context(), stored.FamilyID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't revoke refresh token family", err)
+			return
+		}
+		// A family that was already dead has nothing left to protect, so
+		// replaying its tokens can't be used to sign the user out again.
+		if revoked > 0 {
+			if _, err := qtx.BumpUserTokenVersion(r.Context(), stored.UserID); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sign-in tokens", err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't revoke refresh token family", err)
+			return
+		}
+		if revoked > 0 {
+			cfg.authUsers.Forget(stored.UserID)
+		}
+		log.Printf("Security: revoked refresh token reused for user %s; revoked %d remaining tokens in family %s", stored.UserID, revoked, stored.FamilyID)
+		respondWithError(w, http.StatusUnauthorized, "Refresh token has already been used", nil)
+		return
+	}
+
+	if !stored.ExpiresAt.After(time.Now()) {
+		respondWithError(w, http.StatusUnauthorized, "Refresh token has expired", nil)
+		return
+	}
+
+	_, err = qtx.RevokeRefreshToken(r.Context(), stored.TokenHash)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rotate refresh token", err)
+		return
+	}
+
+	err = qtx.TouchSession(r.Context(), database.TouchSessionParams{
+		ID:            stored.FamilyID,
+		LastUserAgent: r.UserAgent(),
+		LastIp:        clientIP(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update session", err)
+		return
+	}
+
+	raw_refresh_tkn, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate refresh token", err)
+		return
+	}
+
+	_, err = qtx.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash: auth.HashRefreshToken(raw_refresh_tkn),
+		UserID:    stored.UserID,
+		FamilyID:  stored.FamilyID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate refresh token", err)
+		return
+	}
+
+	user, err := qtx.GetUserByID(r.Context(), stored.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate a new sign-in token", err)
+		return
+	}
+
+	new_jwt, err := auth.MakeJWT(stored.UserID, user.TokenVersion, user.Role, cfg.key, time.Duration((60*60)*time.Second))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't generate a new sign-in token", err)
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't refresh sign-in token", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, response{
-		Token: new_jwt,
+		Token:        new_jwt,
+		RefreshToken: raw_refresh_tkn,
 	})
 }
 
@@ -42,11 +159,47 @@ func (cfg *apiConfig) handlerRevokeRefreshToken(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err1 := cfg.db.RevokeRefreshToken(r.Context(), grab_refresh_tkn)
-	if err1 != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke supplied refresh token", err1)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke supplied refresh token", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	stored, err := qtx.GetRefreshTokenForUpdate(r.Context(), auth.HashRefreshToken(grab_refresh_tkn))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusNoContent, "")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke supplied refresh token", err)
+		return
+	}
+
+	revoked, err := qtx.RevokeRefreshToken(r.Context(), stored.TokenHash)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke supplied refresh token", err)
 		return
 	}
 
+	// Logging out also ends the access tokens issued to this user. Replaying
+	// an already revoked token changes nothing, so it can't be used to sign
+	// the user out over and over.
+	if revoked > 0 {
+		if _, err := qtx.BumpUserTokenVersion(r.Context(), stored.UserID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sign-in tokens", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke supplied refresh token", err)
+		return
+	}
+	if revoked > 0 {
+		cfg.authUsers.Forget(stored.UserID)
+	}
+
 	respondWithJSON(w, http.StatusNoContent, "")
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Must run before the original is restored: it matches plain rechirps on
+	// the original's deleted_at.
+	err = qtx.RestorePlainRechirpsOf(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore rechirps of chirp", err)
+		return
+	}
+
+	// A plain rechirp is only restored while its original is live; without it
+	// the rechirp would come back as an empty chirp.
+	restored, err := qtx.RestoreChirpWithID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find a restorable deleted chirp that has the given ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore chirp", err)
+		return
+	}
+
+	err = qtx.AdjustChirpHashtagUsage(r.Context(), database.AdjustChirpHashtagUsageParams{
+		Delta:   1,
+		ChirpID: restored.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update hashtag usage", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpFromDB(restored))
+}
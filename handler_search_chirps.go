@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"example.com/m/internal/database"
+)
+
+// handlerSearchChirps runs a full-text search over chirp bodies. The query
+// uses websearch syntax, so "quoted phrases", OR and -excluded terms work.
+// Results are ordered by relevance, best match first.
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, "Search query must not be empty", nil)
+		return
+	}
+
+	if r.URL.Query().Has("sort") {
+		respondWithError(w, http.StatusBadRequest, "Search results are always sorted by relevance", nil)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if page.cursor != nil && page.cursor.Rank == nil {
+		respondWithError(w, http.StatusBadRequest, "Cursor is not from a search", nil)
+		return
+	}
+
+	authorID, err := parseAuthorFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parsed provided query parameter as ID", err)
+		return
+	}
+
+	cursorRank := sql.NullFloat64{}
+	_, cursorID := page.cursorArgs()
+	if page.cursor != nil {
+		cursorRank = sql.NullFloat64{Float64: float64(*page.cursor.Rank), Valid: true}
+	}
+
+	results, err := cfg.db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:      query,
+		AuthorID:   authorID,
+		CursorRank: cursorRank,
+		CursorID:   cursorID,
+		PageLimit:  page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't search chirps", err)
+		return
+	}
+
+	results, hasMore := splitPage(results, page)
+	resp := chirpPage{Chirps: []Chirp{}}
+	for _, result := range results {
+		resp.Chirps = append(resp.Chirps, chirpFromDB(result.Chirp))
+	}
+	if hasMore {
+		last := results[len(results)-1]
+		resp.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.Chirp.CreatedAt, ID: last.Chirp.ID, Rank: &last.Rank})
+	}
+
+	err = cfg.hydrateChirps(r.Context(), cfg.optionalViewer(r), resp.refs())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
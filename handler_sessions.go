@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+// Session is one signed-in device: a refresh token family, the client that
+// signed in and the client that last used it.
+type Session struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+	UserAgent     string    `json:"user_agent"`
+	IP            string    `json:"ip"`
+	LastUserAgent string    `json:"last_user_agent"`
+	LastIP        string    `json:"last_ip"`
+}
+
+func (cfg *apiConfig) handlerGetSessions(w http.ResponseWriter, r *http.Request) {
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	dbSessions, err := cfg.db.GetActiveSessionsByUser(r.Context(), idFromToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get sessions", err)
+		return
+	}
+
+	sessions := []Session{}
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, sessionFromDB(dbSession))
+	}
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	revoked, err := cfg.db.RevokeSession(r.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   idFromToken,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find active session that has the given ID", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerRevokeAllSessions signs the caller out everywhere, including every
+// access token already handed out.
+func (cfg *apiConfig) handlerRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	_, err = qtx.RevokeAllSessions(r.Context(), idFromToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	if _, err := qtx.BumpUserTokenVersion(r.Context(), idFromToken); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sign-in tokens", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	cfg.authUsers.Forget(idFromToken)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// startSession signs userID in on the requesting client: it opens a session
+// and returns the first refresh token of its family.
+func startSession(r *http.Request, q *database.Queries, userID uuid.UUID) (string, error) {
+	rawToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	session, err := q.CreateSession(r.Context(), database.CreateSessionParams{
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		Ip:        clientIP(r),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = q.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash: auth.HashRefreshToken(rawToken),
+		UserID:    userID,
+		FamilyID:  session.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// clientIP is the address the request came from. X-Forwarded-For is ignored
+// since anyone can set it.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func sessionFromDB(dbSession database.Session) Session {
+	return Session{
+		ID:            dbSession.ID,
+		CreatedAt:     dbSession.CreatedAt,
+		LastUsedAt:    dbSession.LastUsedAt,
+		UserAgent:     dbSession.UserAgent,
+		IP:            dbSession.Ip,
+		LastUserAgent: dbSession.LastUserAgent,
+		LastIP:        dbSession.LastIp,
+	}
+}
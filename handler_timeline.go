@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, r *http.Request) {
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorArgs()
+	var chirpsArray []database.Chirp
+	if page.desc {
+		chirpsArray, err = cfg.db.GetTimelinePageDesc(r.Context(), database.GetTimelinePageDescParams{
+			FollowerID:      idFromToken,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	} else {
+		chirpsArray, err = cfg.db.GetTimelinePageAsc(r.Context(), database.GetTimelinePageAscParams{
+			FollowerID:      idFromToken,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       page.fetchLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get timeline", err)
+		return
+	}
+
+	resp := newChirpPage(chirpsArray, page)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: idFromToken, Valid: true}, resp.refs())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't load chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
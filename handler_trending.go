@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+func (cfg *apiConfig) handlerGetTrending(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Window     string        `json:"window"`
+		Tags       []TrendingTag `json:"tags"`
+		ComputedAt time.Time     `json:"computed_at"`
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "24h"
+	}
+	if _, ok := trendingWindows[window]; !ok {
+		respondWithError(w, http.StatusBadRequest, "window must be one of 1h, 24h or 7d", nil)
+		return
+	}
+
+	snapshot, ok := cfg.trending.get(window)
+	if !ok {
+		respondWithError(w, http.StatusServiceUnavailable, "Trending hashtags are still being computed", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Window:     window,
+		Tags:       snapshot.Tags,
+		ComputedAt: snapshot.ComputedAt,
+	})
+}
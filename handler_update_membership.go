@@ -1,59 +1,191 @@
 package main
 
 import (
+	"crypto/subtle"
+	"database/sql"
 	"encoding/json"
-
+	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
 	"github.com/google/uuid"
 )
 
+const (
+	webhookEventsLimit      = 100
+	defaultWebhookTolerance = 5 * time.Minute
+)
+
+// membershipEvents maps the Polka events we act on to the Chirpy Red status
+// they leave the user with.
+var membershipEvents = map[string]bool{
+	"user.upgraded":   true,
+	"user.downgraded": false,
+	"user.refunded":   false,
+}
+
+type WebhookEvent struct {
+	ID         string          `json:"id"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Event      string          `json:"event"`
+	UserID     *uuid.UUID      `json:"user_id,omitempty"`
+	Status     string          `json:"status"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
 func (cfg *apiConfig) handlerUpdateMembership(w http.ResponseWriter, r *http.Request) {
 	type payload struct {
 		UserID string `json:"user_id"`
 	}
 
 	type parameters struct {
+		ID    string  `json:"id"`
 		Event string  `json:"event"`
 		Data  payload `json:"data"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err := decoder.Decode(&params)
-
+	raw, err := io.ReadAll(r.Body)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't read request body", err)
 		return
 	}
 
-	api, err := auth.GetAPIKey(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't get API key to authenticate", err)
+	if err := cfg.authenticateWebhook(r.Header, raw); err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error(), err)
 		return
 	}
 
-	if cfg.api != api {
-		respondWithError(w, http.StatusUnauthorized, "Invalid API key provided", err)
+	params := parameters{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
-	if params.Event != "user.upgraded" {
-		respondWithJSON(w, http.StatusNoContent, nil)
+	// The event ID is what makes redelivery idempotent, so it is required.
+	if params.ID == "" {
+		respondWithError(w, http.StatusBadRequest, "Webhook event is missing an id", nil)
 		return
 	}
 
-	idToUpgrade, err := uuid.Parse(params.Data.UserID)
+	isChirpyRed, known := membershipEvents[params.Event]
+
+	userID := uuid.NullUUID{}
+	if known {
+		userID.UUID, err = uuid.Parse(params.Data.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Couldn't extract user_id from request", err)
+			return
+		}
+		userID.Valid = true
+	}
+
+	status := "ignored"
+	if known {
+		status = "processed"
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't extract user_id from request", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process webhook", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	err1 := cfg.db.UpdateUserMembershipByID(r.Context(), idToUpgrade)
-	if err1 != nil {
-		respondWithError(w, http.StatusNotFound, "Couldn't find user with provided id", err)
+	recorded, err := qtx.CreateWebhookEvent(r.Context(), database.CreateWebhookEventParams{
+		ID:      params.ID,
+		Event:   params.Event,
+		UserID:  userID,
+		Status:  status,
+		Payload: raw,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record webhook", err)
+		return
+	}
+	if recorded == 0 {
+		// Already handled this event; acknowledge so Polka stops retrying.
+		respondWithJSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	if known {
+		updated, err := qtx.UpdateUserMembershipByID(r.Context(), database.UpdateUserMembershipByIDParams{
+			ID:          userID.UUID,
+			IsChirpyRed: sql.NullBool{Bool: isChirpyRed, Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update membership", err)
+			return
+		}
+		// Rolling back leaves the event unrecorded, so a retry once the user
+		// exists is still processed.
+		if updated == 0 {
+			respondWithError(w, http.StatusNotFound, "Couldn't find user with provided id", nil)
+			return
+		}
+
+		if isChirpyRed {
+			err = cfg.enqueueWebhook(r.Context(), qtx, userID.UUID, webhookUserUpgraded, userUpgradedEvent{UserID: userID.UUID})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't queue membership webhooks", err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process webhook", err)
+		return
+	}
+	if known {
+		cfg.authUsers.Forget(userID.UUID)
 	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
+
+// authenticateWebhook accepts a Polka request signed with one of the
+// configured secrets. Unsigned requests fall back to the static ApiKey when
+// one is configured.
+func (cfg *apiConfig) authenticateWebhook(headers http.Header, body []byte) error {
+	if len(cfg.polkaSecrets) > 0 && (headers.Get(auth.SignatureHeader) != "" || cfg.api == "") {
+		return auth.VerifyWebhookSignature(headers, body, cfg.polkaSecrets, cfg.polkaTolerance, time.Now())
+	}
+
+	api, err := auth.GetAPIKey(headers)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(cfg.api), []byte(api)) != 1 {
+		return errors.New("Invalid API key provided")
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerGetWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	dbEvents, err := cfg.db.GetWebhookEvents(r.Context(), webhookEventsLimit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhook events", err)
+		return
+	}
+
+	events := []WebhookEvent{}
+	for _, dbEvent := range dbEvents {
+		event := WebhookEvent{
+			ID:         dbEvent.ID,
+			ReceivedAt: dbEvent.ReceivedAt,
+			Event:      dbEvent.Event,
+			Status:     dbEvent.Status,
+			Payload:    dbEvent.Payload,
+		}
+		if dbEvent.UserID.Valid {
+			event.UserID = &dbEvent.UserID.UUID
+		}
+		events = append(events, event)
+	}
+	respondWithJSON(w, http.StatusOK, events)
+}
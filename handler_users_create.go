@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/mail"
 	"time"
 
 	"example.com/m/internal/auth"
@@ -25,6 +27,7 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 	}
 	type response struct {
 		User
+		EmailVerified bool `json:"email_verified"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -35,12 +38,25 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if address, err := mail.ParseAddress(params.Email); err != nil || address.Address != params.Email {
+		respondWithError(w, http.StatusBadRequest, "Email must be a valid email address", err)
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't encrypt password", err)
 	}
 
-	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.CreateUser(r.Context(), database.CreateUserParams{
 		HashedPassword: hashedPassword,
 		Email:          params.Email})
 	if err != nil {
@@ -48,6 +64,23 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	verificationToken, err := cfg.createEmailVerification(r.Context(), qtx, user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
+		return
+	}
+
+	// The account exists either way; if the email doesn't go out the user
+	// can ask for it again from /api/users/verify/resend.
+	sendInBackground("verification email", func(ctx context.Context) error {
+		return cfg.sendVerificationEmail(ctx, user.Email, verificationToken)
+	})
+
 	respondWithJSON(w, http.StatusCreated, response{
 		User: User{
 			ID:          user.ID,
@@ -56,6 +89,7 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 			Email:       user.Email,
 			IsChirpyRed: user.IsChirpyRed.Bool,
 		},
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	})
 }
 
@@ -72,6 +106,7 @@ func (cfg *apiConfig) handlerUsersLogin(w http.ResponseWriter, r *http.Request)
 		Token        string    `json:"token"`
 		RefreshToken string    `json:"refresh_token"`
 		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		Role         string    `json:"role"`
 	}
 
 	const maxExpirySeconds = 60 * 60
@@ -98,38 +133,47 @@ func (cfg *apiConfig) handlerUsersLogin(w http.ResponseWriter, r *http.Request)
 	if !match {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
-	} else {
-		created_token, err := auth.MakeJWT(grab_user.ID, cfg.key, time.Duration(maxExpirySeconds)*time.Second)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Couldn't generate sign-in token", err)
-			return
-		}
+	}
+	if !grab_user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Verify your email address before logging in", nil)
+		return
+	}
 
-		raw_refresh_tkn, err := auth.MakeRefreshToken()
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Couldn't generate refresh token", err)
-			return
-		}
+	created_token, err := auth.MakeJWT(grab_user.ID, grab_user.TokenVersion, grab_user.Role, cfg.key, time.Duration(maxExpirySeconds)*time.Second)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate sign-in token", err)
+		return
+	}
 
-		refresh_tkn, err := cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-			Token:  raw_refresh_tkn,
-			UserID: grab_user.ID,
-		})
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Couldn't generate refresh token", err)
-			return
-		}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start session", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-		respondWithJSON(w, http.StatusOK, response{
-			ID:           grab_user.ID,
-			CreatedAt:    grab_user.CreatedAt,
-			UpdatedAt:    grab_user.UpdatedAt,
-			Email:        grab_user.Email,
-			Token:        created_token,
-			RefreshToken: refresh_tkn.Token,
-			IsChirpyRed:  grab_user.IsChirpyRed.Bool,
-		})
+	raw_refresh_tkn, err := startSession(r, qtx, grab_user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start session", err)
+		return
 	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start session", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		ID:           grab_user.ID,
+		CreatedAt:    grab_user.CreatedAt,
+		UpdatedAt:    grab_user.UpdatedAt,
+		Email:        grab_user.Email,
+		Token:        created_token,
+		RefreshToken: raw_refresh_tkn,
+		IsChirpyRed:  grab_user.IsChirpyRed.Bool,
+		Role:         grab_user.Role,
+	})
 }
 
 func (cfg *apiConfig) handlerUserLoginUpdate(w http.ResponseWriter, r *http.Request) {
@@ -139,7 +183,10 @@ func (cfg *apiConfig) handlerUserLoginUpdate(w http.ResponseWriter, r *http.Requ
 	}
 
 	type response struct {
-		Email string `json:"email"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Token         string `json:"token"`
+		RefreshToken  string `json:"refresh_token"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -151,24 +198,35 @@ func (cfg *apiConfig) handlerUserLoginUpdate(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	bearerToken, err := auth.GetBearerToken(r.Header)
+	if address, err := mail.ParseAddress(params.Email); err != nil || address.Address != params.Email {
+		respondWithError(w, http.StatusBadRequest, "Email must be a valid email address", err)
+		return
+	}
+
+	caller := auth.MustUserFromContext(r.Context())
+	idFromToken := caller.ID
+
+	newHashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Error obtaining sign-in token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't encrypt password", err)
 		return
 	}
 
-	idFromToken, err := auth.ValidateJWT(bearerToken, cfg.key)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Error validating sign-in token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update credentials", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	newHashedPassword, err := auth.HashPassword(params.Password)
+	current, err := qtx.GetUserByID(r.Context(), idFromToken)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't encrypt password", err)
+		respondWithError(w, http.StatusUnauthorized, "Authentication token invalid or missing", err)
+		return
 	}
 
-	userNewCreds, err := cfg.db.UpdateUserPwdEmailByToken(r.Context(), database.UpdateUserPwdEmailByTokenParams{
+	userNewCreds, err := qtx.UpdateUserPwdEmailByToken(r.Context(), database.UpdateUserPwdEmailByTokenParams{
 		Email:          params.Email,
 		HashedPassword: newHashedPassword,
 		ID:             idFromToken,
@@ -178,7 +236,58 @@ func (cfg *apiConfig) handlerUserLoginUpdate(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// A changed address is unverified again until the new one is confirmed.
+	verificationToken := ""
+	if userNewCreds.Email != current.Email {
+		verificationToken, err = cfg.createEmailVerification(r.Context(), qtx, idFromToken)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update credentials", err)
+			return
+		}
+	}
+
+	// New credentials sign out everything issued with the old ones. Access
+	// tokens don't say which session they came from, so the caller's session
+	// goes too and is replaced along with their access token.
+	tokenVersion, err := qtx.BumpUserTokenVersion(r.Context(), idFromToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke old sign-in tokens", err)
+		return
+	}
+
+	if _, err := qtx.RevokeAllSessions(r.Context(), idFromToken); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	refreshToken, err := startSession(r, qtx, idFromToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start session", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update credentials", err)
+		return
+	}
+	cfg.authUsers.Forget(idFromToken)
+
+	if verificationToken != "" {
+		sendInBackground("verification email", func(ctx context.Context) error {
+			return cfg.sendVerificationEmail(ctx, userNewCreds.Email, verificationToken)
+		})
+	}
+
+	newToken, err := auth.MakeJWT(idFromToken, tokenVersion, caller.Role, cfg.key, time.Hour)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate sign-in token", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, response{
-		Email: userNewCreds,
+		Email:         userNewCreds.Email,
+		EmailVerified: userNewCreds.EmailVerifiedAt.Valid,
+		Token:         newToken,
+		RefreshToken:  refreshToken,
 	})
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/m/internal/textlen"
+)
+
+// handlerValidateChirp checks a draft without posting it, so clients can show
+// a live remaining-character count. Signed-in callers get their own limit.
+func (cfg *apiConfig) handlerValidateChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	type response struct {
+		Valid     bool   `json:"valid"`
+		Length    int    `json:"length"`
+		MaxLength int    `json:"max_length"`
+		Remaining int    `json:"remaining"`
+		Error     string `json:"error,omitempty"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	maxLength := cfg.userTier(r.Context()).MaxChirpLength
+
+	length := textlen.Weighted(params.Body)
+	resp := response{
+		Valid:     true,
+		Length:    length,
+		MaxLength: maxLength,
+		Remaining: maxLength - length,
+	}
+
+	_, err = cfg.validateChirp(params.Body, maxLength)
+	if err != nil {
+		resp.Valid = false
+		resp.Error = err.Error()
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
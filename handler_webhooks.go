@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+const webhookDeliveriesLimit = 100
+
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	// Secret is only returned when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryAttempt struct {
+	CreatedAt  time.Time `json:"created_at"`
+	StatusCode *int32    `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID                `json:"id"`
+	CreatedAt     time.Time                `json:"created_at"`
+	Event         string                   `json:"event"`
+	Status        string                   `json:"status"`
+	Attempts      int32                    `json:"attempts"`
+	NextAttemptAt *time.Time               `json:"next_attempt_at,omitempty"`
+	Payload       json.RawMessage          `json:"payload"`
+	Log           []WebhookDeliveryAttempt `json:"log"`
+}
+
+func (cfg *apiConfig) handlerWebhooksCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	user := auth.MustUserFromContext(r.Context())
+
+	if !cfg.userTier(r.Context()).CanUseWebhooks && !auth.HasRole(user, auth.RoleAdmin) {
+		respondWithError(w, http.StatusForbidden, "Webhooks require a Chirpy Red membership", nil)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	target, err := url.Parse(params.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		respondWithError(w, http.StatusBadRequest, "Webhook url must be an absolute http or https URL", err)
+		return
+	}
+	if err := checkWebhookHost(r.Context(), target.Hostname()); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Webhook url must resolve to a public address", err)
+		return
+	}
+
+	if len(params.Events) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Webhook must subscribe to at least one event", nil)
+		return
+	}
+	events := []string{}
+	for _, event := range params.Events {
+		if !slices.Contains(webhookEventTypes, event) {
+			respondWithError(w, http.StatusBadRequest, "Unknown webhook event: "+event, nil)
+			return
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create webhook secret", err)
+		return
+	}
+
+	subscription, err := cfg.db.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		UserID: user.ID,
+		Url:    target.String(),
+		Secret: secret,
+		Events: events,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create webhook", err)
+		return
+	}
+
+	response := webhookSubscriptionFromDB(subscription)
+	response.Secret = subscription.Secret
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *apiConfig) handlerWebhooksList(w http.ResponseWriter, r *http.Request) {
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	dbSubscriptions, err := cfg.db.GetWebhookSubscriptionsByUser(r.Context(), idFromToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhooks", err)
+		return
+	}
+
+	subscriptions := []WebhookSubscription{}
+	for _, dbSubscription := range dbSubscriptions {
+		subscriptions = append(subscriptions, webhookSubscriptionFromDB(dbSubscription))
+	}
+	respondWithJSON(w, http.StatusOK, subscriptions)
+}
+
+func (cfg *apiConfig) handlerWebhooksDelete(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	deleted, err := cfg.db.DeleteWebhookSubscription(r.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:     webhookID,
+		UserID: idFromToken,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete webhook", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find webhook that has the given ID", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerWebhookDeliveries returns the most recent deliveries for one of the
+// caller's webhooks, each with the log of its attempts.
+func (cfg *apiConfig) handlerWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	dbDeliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          webhookDeliveriesLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhook deliveries", err)
+		return
+	}
+
+	deliveryIDs := make([]uuid.UUID, 0, len(dbDeliveries))
+	for _, dbDelivery := range dbDeliveries {
+		deliveryIDs = append(deliveryIDs, dbDelivery.ID)
+	}
+	dbAttempts, err := cfg.db.GetWebhookDeliveryAttempts(r.Context(), deliveryIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhook delivery log", err)
+		return
+	}
+	attempts := map[uuid.UUID][]WebhookDeliveryAttempt{}
+	for _, dbAttempt := range dbAttempts {
+		attempt := WebhookDeliveryAttempt{
+			CreatedAt: dbAttempt.CreatedAt,
+			Error:     dbAttempt.Error.String,
+		}
+		if dbAttempt.StatusCode.Valid {
+			attempt.StatusCode = &dbAttempt.StatusCode.Int32
+		}
+		attempts[dbAttempt.DeliveryID] = append(attempts[dbAttempt.DeliveryID], attempt)
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, dbDelivery := range dbDeliveries {
+		delivery := WebhookDelivery{
+			ID:        dbDelivery.ID,
+			CreatedAt: dbDelivery.CreatedAt,
+			Event:     dbDelivery.Event,
+			Status:    dbDelivery.Status,
+			Attempts:  dbDelivery.Attempts,
+			Payload:   dbDelivery.Payload,
+			Log:       attempts[dbDelivery.ID],
+		}
+		if delivery.Log == nil {
+			delivery.Log = []WebhookDeliveryAttempt{}
+		}
+		if dbDelivery.Status == "pending" {
+			delivery.NextAttemptAt = &dbDelivery.NextAttemptAt
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// handlerRetryWebhookDelivery puts a dead delivery back in the queue with a
+// fresh set of attempts.
+func (cfg *apiConfig) handlerRetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return
+	}
+
+	subscription, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	retried, err := cfg.db.RetryWebhookDelivery(r.Context(), database.RetryWebhookDeliveryParams{
+		ID:             deliveryID,
+		SubscriptionID: subscription.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retry webhook delivery", err)
+		return
+	}
+	if retried == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find dead delivery that has the given ID", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// ownedWebhook loads the {webhookID} subscription for the signed-in user,
+// writing the error response itself when it can't.
+func (cfg *apiConfig) ownedWebhook(w http.ResponseWriter, r *http.Request) (database.WebhookSubscription, bool) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode url parameters to string for internal use", err)
+		return database.WebhookSubscription{}, false
+	}
+
+	idFromToken := auth.MustUserFromContext(r.Context()).ID
+
+	subscription, err := cfg.db.GetWebhookSubscriptionByID(r.Context(), webhookID)
+	if err != nil || subscription.UserID != idFromToken {
+		respondWithError(w, http.StatusNotFound, "Couldn't find webhook that has the given ID", err)
+		return database.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func webhookSubscriptionFromDB(dbSubscription database.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        dbSubscription.ID,
+		CreatedAt: dbSubscription.CreatedAt,
+		UpdatedAt: dbSubscription.UpdatedAt,
+		URL:       dbSubscription.Url,
+		Events:    dbSubscription.Events,
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -29,39 +30,62 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return match, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
+// claims adds the user's token version and role to the registered claims.
+// Tokens issued before versions existed have no "ver" and read as version 0;
+// tokens without a role belong to ordinary users.
+type claims struct {
+	jwt.RegisteredClaims
+	TokenVersion int32  `json:"ver"`
+	Role         string `json:"role,omitempty"`
+}
 
-	ss, err := token.SignedString([]byte(tokenSecret))
+func MakeJWT(userID uuid.UUID, tokenVersion int32, role string, keys *Keyring, expiresIn time.Duration) (string, error) {
+	ss, err := keys.sign(claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		TokenVersion: tokenVersion,
+		Role:         role,
+	})
 	return ss, err
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+// ValidateJWT checks the token's signature and expiry. Whether the user has
+// revoked their tokens since is up to the Authenticator.
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	user, err := validateJWT(tokenString, keys)
+	return user.ID, err
+}
 
-		return []byte(tokenSecret), nil
-	})
+// validateJWT is ValidateJWT returning the version and role claims as well.
+func validateJWT(tokenString string, keys *Keyring) (User, error) {
+	claims := &claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.verifyKey)
 
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("Error parsing token: %v", err)
+		return User{}, fmt.Errorf("Error parsing token: %v", err)
+	}
+	// Access tokens have no audience; anything with one was issued for
+	// something else.
+	if len(claims.Audience) > 0 {
+		return User{}, fmt.Errorf("Token is not an access token")
 	}
 
 	idString := claims.Subject
 
 	realId, err := uuid.Parse(idString)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("Error parsing result to id format: %v", err)
+		return User{}, fmt.Errorf("Error parsing result to id format: %v", err)
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = RoleUser
 	}
-	return realId, nil
+	return User{ID: realId, Role: role, TokenVersion: claims.TokenVersion}, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -85,10 +109,34 @@ func GetBearerToken(headers http.Header) (string, error) {
 
 func MakeRefreshToken() (string, error) {
 	key := make([]byte, 32)
-	rand.Read(key)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("Error generating refresh token: %v", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// MakePasswordResetToken returns a random one-time token to mail to a user
+// who forgot their password. Only its HashPasswordResetToken is stored.
+func MakePasswordResetToken() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("Error generating password reset token: %v", err)
+	}
 	return hex.EncodeToString(key), nil
 }
 
+// HashRefreshToken is what refresh tokens are stored and looked up by, so a
+// copy of the database doesn't hand out live sessions.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPasswordResetToken is what reset tokens are stored and looked up by.
+func HashPasswordResetToken(token string) string {
+	return HashRefreshToken(token)
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	auth := headers.Get("Authorization")
 	if len(auth) == 0 {
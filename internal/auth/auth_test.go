@@ -1,6 +1,19 @@
 package auth
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,11 +22,11 @@ import (
 
 func TestNormalToken(t *testing.T) {
 	userId := uuid.New()
-	ss, err1 := MakeJWT(userId, "Vertigo", time.Hour)
+	ss, err1 := MakeJWT(userId, 0, RoleUser, NewHMACKeyring("Vertigo"), time.Hour)
 	if err1 != nil {
 		t.Fatalf("Error making a JWT token: %v", err1)
 	}
-	validatedId, err2 := ValidateJWT(ss, "Vertigo")
+	validatedId, err2 := ValidateJWT(ss, NewHMACKeyring("Vertigo"))
 	if err2 != nil {
 		t.Fatalf("Error validating JWT token: %v", err2)
 	}
@@ -25,11 +38,11 @@ func TestNormalToken(t *testing.T) {
 
 func TestExpiredToken(t *testing.T) {
 	userId := uuid.New()
-	ss, err1 := MakeJWT(userId, "Vertigo", -time.Hour)
+	ss, err1 := MakeJWT(userId, 0, RoleUser, NewHMACKeyring("Vertigo"), -time.Hour)
 	if err1 != nil {
 		t.Fatalf("Error making a JWT token: %v", err1)
 	}
-	_, err2 := ValidateJWT(ss, "Vertigo")
+	_, err2 := ValidateJWT(ss, NewHMACKeyring("Vertigo"))
 	if err2 == nil {
 		t.Fatalf("Error invalidating an expired JWT token")
 	}
@@ -37,11 +50,11 @@ func TestExpiredToken(t *testing.T) {
 
 func TestWrongSecret(t *testing.T) {
 	userId := uuid.New()
-	ss, err1 := MakeJWT(userId, "secret1", -time.Hour)
+	ss, err1 := MakeJWT(userId, 0, RoleUser, NewHMACKeyring("secret1"), -time.Hour)
 	if err1 != nil {
 		t.Fatalf("Error making a JWT token: %v", err1)
 	}
-	_, err2 := ValidateJWT(ss, "secret2")
+	_, err2 := ValidateJWT(ss, NewHMACKeyring("secret2"))
 	if err2 == nil {
 		t.Fatalf("Error invalidating a JWT with mismatching secrets")
 	}
@@ -49,12 +62,399 @@ func TestWrongSecret(t *testing.T) {
 
 func TestGarbageTokenString(t *testing.T) {
 	userId := uuid.New()
-	ss, err1 := MakeJWT(userId, "Vertigo", -time.Hour)
+	ss, err1 := MakeJWT(userId, 0, RoleUser, NewHMACKeyring("Vertigo"), -time.Hour)
 	if err1 != nil {
 		t.Fatalf("Error making a JWT token: %v", err1)
 	}
-	_, err2 := ValidateJWT("Modified token"+ss, "Vertigo")
+	_, err2 := ValidateJWT("Modified token"+ss, NewHMACKeyring("Vertigo"))
 	if err2 == nil {
 		t.Fatalf("Error invalidating a modified JWT token")
 	}
 }
+
+func signedHeaders(secret string, timestamp time.Time, body []byte) http.Header {
+	headers := http.Header{}
+	headers.Set(SignatureHeader, "sha256="+SignWebhook(secret, timestamp, body))
+	headers.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	return headers
+}
+
+func TestWebhookSignatureValid(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded"}`)
+	now := time.Now()
+	headers := signedHeaders("current", now, body)
+	if err := VerifyWebhookSignature(headers, body, []string{"current"}, 5*time.Minute, now); err != nil {
+		t.Fatalf("Expected valid signature, got: %v", err)
+	}
+}
+
+func TestWebhookSignatureRotatedKey(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded"}`)
+	now := time.Now()
+	headers := signedHeaders("previous", now, body)
+	if err := VerifyWebhookSignature(headers, body, []string{"current", "previous"}, 5*time.Minute, now); err != nil {
+		t.Fatalf("Expected previous key to be accepted, got: %v", err)
+	}
+}
+
+func TestWebhookSignatureTamperedBody(t *testing.T) {
+	now := time.Now()
+	headers := signedHeaders("current", now, []byte(`{"event":"user.upgraded"}`))
+	err := VerifyWebhookSignature(headers, []byte(`{"event":"user.refunded"}`), []string{"current"}, 5*time.Minute, now)
+	if !errors.Is(err, ErrSignatureMismatch) {
+		t.Fatalf("Expected ErrSignatureMismatch, got: %v", err)
+	}
+}
+
+func TestWebhookSignatureStale(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded"}`)
+	now := time.Now()
+	headers := signedHeaders("current", now.Add(-10*time.Minute), body)
+	err := VerifyWebhookSignature(headers, body, []string{"current"}, 5*time.Minute, now)
+	if !errors.Is(err, ErrSignatureTimestamp) {
+		t.Fatalf("Expected ErrSignatureTimestamp, got: %v", err)
+	}
+}
+
+func TestWebhookSignatureMissing(t *testing.T) {
+	err := VerifyWebhookSignature(http.Header{}, nil, []string{"current"}, 5*time.Minute, time.Now())
+	if !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("Expected ErrNoSignature, got: %v", err)
+	}
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("Error making refresh token: %v", err)
+	}
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Fatalf("Hash should not equal the raw token")
+	}
+	if hash != HashRefreshToken(token) {
+		t.Fatalf("Hashing the same token twice should give the same hash")
+	}
+	if len(hash) != 64 {
+		t.Fatalf("Expected a hex SHA-256 hash, got %q", hash)
+	}
+}
+
+func writeKey(t *testing.T, dir, kid string, key any, public bool) {
+	t.Helper()
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatalf("Error encoding public key: %v", err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Error encoding private key: %v", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("Error writing key: %v", err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	_, oldKey, _ := ed25519.GenerateKey(nil)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %v", err)
+	}
+	writeKey(t, dir, "old", oldKey, false)
+	writeKey(t, dir, "new", rsaKey, false)
+
+	oldRing, err := LoadKeyring(dir, "old", "")
+	if err != nil {
+		t.Fatalf("Error loading keyring: %v", err)
+	}
+	userId := uuid.New()
+	oldToken, err := MakeJWT(userId, 0, RoleUser, oldRing, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+
+	// Rotate: "new" signs, "old" is retired to its public half.
+	writeKey(t, dir, "old", oldKey.Public(), true)
+	newRing, err := LoadKeyring(dir, "new", "")
+	if err != nil {
+		t.Fatalf("Error loading rotated keyring: %v", err)
+	}
+	validatedId, err := ValidateJWT(oldToken, newRing)
+	if err != nil {
+		t.Fatalf("Token signed by retired key should still validate: %v", err)
+	}
+	if validatedId != userId {
+		t.Errorf("initial id: %v, received id: %v", userId, validatedId)
+	}
+
+	newToken, err := MakeJWT(userId, 0, RoleUser, newRing, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+	if _, err := ValidateJWT(newToken, newRing); err != nil {
+		t.Fatalf("Error validating RS256 token: %v", err)
+	}
+
+	if len(newRing.JWKS().Keys) != 2 {
+		t.Errorf("Expected both keys in JWKS, got %+v", newRing.JWKS())
+	}
+}
+
+func TestKeyringRetiredKeyCannotSign(t *testing.T) {
+	dir := t.TempDir()
+	public, _, _ := ed25519.GenerateKey(nil)
+	writeKey(t, dir, "retired", public, true)
+	if _, err := LoadKeyring(dir, "retired", ""); err == nil {
+		t.Fatalf("Expected a public-only key to be refused as the active key")
+	}
+}
+
+func TestKeyringLegacySecret(t *testing.T) {
+	dir := t.TempDir()
+	_, key, _ := ed25519.GenerateKey(nil)
+	writeKey(t, dir, "current", key, false)
+
+	userId := uuid.New()
+	legacyToken, err := MakeJWT(userId, 0, RoleUser, NewHMACKeyring("Vertigo"), time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+
+	withLegacy, err := LoadKeyring(dir, "current", "Vertigo")
+	if err != nil {
+		t.Fatalf("Error loading keyring: %v", err)
+	}
+	if _, err := ValidateJWT(legacyToken, withLegacy); err != nil {
+		t.Fatalf("HS256 token should validate while the legacy secret is configured: %v", err)
+	}
+
+	withoutLegacy, err := LoadKeyring(dir, "current", "")
+	if err != nil {
+		t.Fatalf("Error loading keyring: %v", err)
+	}
+	if _, err := ValidateJWT(legacyToken, withoutLegacy); err == nil {
+		t.Fatalf("HS256 token should be rejected without the legacy secret")
+	}
+}
+
+func TestRevokedTokenVersion(t *testing.T) {
+	userId := uuid.New()
+	version := int32(0)
+	keys := NewHMACKeyring("Vertigo")
+	users := NewUserCache(func(ctx context.Context, id uuid.UUID) (User, error) {
+		return User{ID: id, TokenVersion: version}, nil
+	}, time.Minute)
+	authn := NewAuthenticator(keys, users.Get)
+	handler := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ss, err := MakeJWT(userId, version, RoleUser, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+	status := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+ss)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := status(); code != http.StatusOK {
+		t.Fatalf("Expected current token to be accepted, got %d", code)
+	}
+
+	version++
+	if code := status(); code != http.StatusOK {
+		t.Fatalf("Cached version should be used until it expires, got %d", code)
+	}
+
+	users.Forget(userId)
+	if code := status(); code != http.StatusUnauthorized {
+		t.Fatalf("Token with an old version should be rejected, got %d", code)
+	}
+}
+
+func TestAuthLookupFailure(t *testing.T) {
+	keys := NewHMACKeyring("Vertigo")
+	authn := NewAuthenticator(keys, func(ctx context.Context, id uuid.UUID) (User, error) {
+		return User{}, errors.New("connection refused")
+	})
+
+	ss, err := MakeJWT(uuid.New(), 0, RoleUser, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+	for _, handler := range []http.Handler{
+		authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		authn.Optional(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+ss)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected a failed user lookup to be a server error, got %d", rec.Code)
+		}
+	}
+}
+
+func testAuthenticator(t *testing.T) (*Authenticator, *Keyring, uuid.UUID) {
+	t.Helper()
+	keys := NewHMACKeyring("Vertigo")
+	userId := uuid.New()
+	authn := NewAuthenticator(keys, func(ctx context.Context, id uuid.UUID) (User, error) {
+		if id != userId {
+			return User{}, ErrUserNotFound
+		}
+		return User{ID: id, IsChirpyRed: true}, nil
+	})
+	return authn, keys, userId
+}
+
+func TestRequiredAuth(t *testing.T) {
+	authn, keys, userId := testAuthenticator(t)
+	var seen User
+	handler := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = MustUserFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Bearer realm="chirpy"` {
+		t.Fatalf("Expected a bare challenge without a token, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer garbage")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("Expected invalid_token challenge, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	ss, err := MakeJWT(userId, 0, RoleUser, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+ss)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || seen.ID != userId || !seen.IsChirpyRed {
+		t.Fatalf("Expected user in context, got %d %+v", rec.Code, seen)
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	authn, _, _ := testAuthenticator(t)
+	called := false
+	handler := authn.Optional(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := UserFromContext(r.Context()); ok {
+			t.Errorf("Expected anonymous request")
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer garbage")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !called || rec.Code != http.StatusOK {
+		t.Fatalf("Expected invalid token to be treated as anonymous, got %d", rec.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	keys := NewHMACKeyring("Vertigo")
+	userId := uuid.New()
+	role := RoleUser
+	authn := NewAuthenticator(keys, func(ctx context.Context, id uuid.UUID) (User, error) {
+		return User{ID: id, Role: role}, nil
+	})
+	handler := authn.RequireRole(RoleModerator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// The token always claims admin; only the stored role counts.
+	ss, err := MakeJWT(userId, 0, RoleAdmin, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+	for _, tc := range []struct {
+		role string
+		code int
+	}{
+		{RoleUser, http.StatusForbidden},
+		{RoleModerator, http.StatusOK},
+		{RoleAdmin, http.StatusOK},
+	} {
+		role = tc.role
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+ss)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("role %q: expected %d, got %d", tc.role, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestEmailVerificationToken(t *testing.T) {
+	keys := NewHMACKeyring("Vertigo")
+	userId, tokenId := uuid.New(), uuid.New()
+	ss, err := MakeEmailVerificationToken(userId, tokenId, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a verification token: %v", err)
+	}
+
+	gotUser, gotToken, err := ValidateEmailVerificationToken(ss, keys)
+	if err != nil {
+		t.Fatalf("Error validating verification token: %v", err)
+	}
+	if gotUser != userId || gotToken != tokenId {
+		t.Errorf("expected %v/%v, got %v/%v", userId, tokenId, gotUser, gotToken)
+	}
+
+	if _, err := ValidateJWT(ss, keys); err == nil {
+		t.Errorf("verification token was accepted as an access token")
+	}
+
+	access, err := MakeJWT(userId, 0, RoleUser, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a JWT token: %v", err)
+	}
+	if _, _, err := ValidateEmailVerificationToken(access, keys); err == nil {
+		t.Errorf("access token was accepted as a verification token")
+	}
+
+	expired, err := MakeEmailVerificationToken(userId, tokenId, keys, -time.Hour)
+	if err != nil {
+		t.Fatalf("Error making a verification token: %v", err)
+	}
+	if _, _, err := ValidateEmailVerificationToken(expired, keys); err == nil {
+		t.Errorf("expired verification token was accepted")
+	}
+}
+
+func TestPasswordResetToken(t *testing.T) {
+	first, err := MakePasswordResetToken()
+	if err != nil {
+		t.Fatalf("Error making password reset token: %v", err)
+	}
+	second, err := MakePasswordResetToken()
+	if err != nil {
+		t.Fatalf("Error making password reset token: %v", err)
+	}
+	if first == second {
+		t.Fatalf("Reset tokens should be random")
+	}
+	if HashPasswordResetToken(first) == first || HashPasswordResetToken(first) == HashPasswordResetToken(second) {
+		t.Fatalf("Reset tokens should be stored by a distinct hash")
+	}
+}
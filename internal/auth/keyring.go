@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is one JWT signing key. Retired keys only carry the public half, so
+// they can still verify tokens issued before a rotation but never sign new
+// ones.
+type Key struct {
+	ID        string
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// Keyring signs tokens with its active key and verifies them with whichever
+// key the token's kid header names. A keyring built from a shared secret signs
+// HS256 tokens without a kid, as Chirpy always has.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+	// legacy verifies kid-less HS256 tokens issued before asymmetric keys
+	// were configured.
+	legacy *Key
+}
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewHMACKeyring(secret string) *Keyring {
+	key := &Key{
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+	return &Keyring{active: key, keys: map[string]*Key{}, legacy: key}
+}
+
+// LoadKeyring reads every *.pem file in dir as a key named after the file.
+// PKCS#8 private keys (RSA for RS256, Ed25519 for EdDSA) can sign; PKIX public
+// keys are kept for verification only. activeKID picks the signing key. A
+// non-empty legacySecret keeps HS256 tokens signed with it valid.
+func LoadKeyring(dir, activeKID, legacySecret string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keyring := &Keyring{keys: map[string]*Key{}}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keyring.keys[kid] = key
+	}
+
+	active, ok := keyring.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found in %s", activeKID, dir)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active signing key %q has no private key", activeKID)
+	}
+	keyring.active = active
+
+	if legacySecret != "" {
+		keyring.legacy = NewHMACKeyring(legacySecret).legacy
+	}
+	return keyring, nil
+}
+
+// ParsePEMKey turns a PEM encoded RSA or Ed25519 key into a Key named kid.
+func ParsePEMKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch private := parsed.(type) {
+		case *rsa.PrivateKey:
+			return &Key{ID: kid, method: jwt.SigningMethodRS256, signKey: private, verifyKey: &private.PublicKey}, nil
+		case ed25519.PrivateKey:
+			return &Key{ID: kid, method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: private.Public()}, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch public := parsed.(type) {
+		case *rsa.PublicKey:
+			return &Key{ID: kid, method: jwt.SigningMethodRS256, verifyKey: public}, nil
+		case ed25519.PublicKey:
+			return &Key{ID: kid, method: jwt.SigningMethodEdDSA, verifyKey: public}, nil
+		}
+		return nil, fmt.Errorf("unsupported public key type %T", parsed)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	if k.active.ID != "" {
+		token.Header["kid"] = k.active.ID
+	}
+	return token.SignedString(k.active.signKey)
+}
+
+// verifyKey is the jwt.Keyfunc for tokens checked against the keyring. The
+// algorithm is pinned to the key's own so a token can't pick a weaker one.
+func (k *Keyring) verifyKey(token *jwt.Token) (any, error) {
+	key := k.legacy
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key = k.keys[kid]
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key: %v", kid)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("token has no signing key id")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS lists the public half of every asymmetric key, active and retired, so
+// other services can verify Chirpy tokens without holding a secret.
+func (k *Keyring) JWKS() JWKS {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,26 @@
+package auth
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRanks orders roles so that each one can do everything the ones below
+// it can.
+var roleRanks = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether user's role is min or above it.
+func HasRole(user User, min string) bool {
+	rank, ok := roleRanks[user.Role]
+	return ok && rank >= roleRanks[min]
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserCache remembers what UserLookup returned for a short while so checking
+// an access token doesn't cost a database round trip on every request. A
+// change made by another server is noticed once the entry expires; Forget
+// makes a local change take effect straight away.
+type UserCache struct {
+	lookup UserLookup
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]userCacheEntry
+}
+
+type userCacheEntry struct {
+	user      User
+	fetchedAt time.Time
+}
+
+func NewUserCache(lookup UserLookup, ttl time.Duration) *UserCache {
+	return &UserCache{
+		lookup:  lookup,
+		ttl:     ttl,
+		entries: map[uuid.UUID]userCacheEntry{},
+	}
+}
+
+// Get is a UserLookup that serves recent results from the cache.
+func (c *UserCache) Get(ctx context.Context, userID uuid.UUID) (User, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.user, nil
+	}
+
+	user, err := c.lookup(ctx, userID)
+	if err != nil {
+		return User{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[userID] = userCacheEntry{user: user, fetchedAt: time.Now()}
+	// Drop stale entries now and then so the map doesn't grow with every
+	// user ever seen.
+	if len(c.entries) > 10000 {
+		for id, e := range c.entries {
+			if time.Since(e.fetchedAt) >= c.ttl {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.mu.Unlock()
+	return user, nil
+}
+
+func (c *UserCache) Forget(userID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// emailVerificationAudience keeps verification tokens and access tokens from
+// being accepted in place of each other.
+const emailVerificationAudience = "chirpy-email-verification"
+
+// MakeEmailVerificationToken signs a token proving the holder received mail
+// sent to userID's address. tokenID names the stored verification, which is
+// what makes the token single-use.
+func MakeEmailVerificationToken(userID, tokenID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	return keys.sign(jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{emailVerificationAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		Subject:   userID.String(),
+		ID:        tokenID.String(),
+	})
+}
+
+// ValidateEmailVerificationToken checks the token's signature and expiry and
+// returns the user and stored verification it names.
+func ValidateEmailVerificationToken(tokenString string, keys *Keyring) (userID, tokenID uuid.UUID, err error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, keys.verifyKey,
+		jwt.WithAudience(emailVerificationAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("Error parsing token: %v", err)
+	}
+
+	userID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("Error parsing result to id format: %v", err)
+	}
+	tokenID, err = uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("Error parsing token id: %v", err)
+	}
+	return userID, tokenID, nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Polka-Signature"
+	TimestampHeader = "X-Polka-Timestamp"
+)
+
+var (
+	ErrNoSignature        = errors.New("Webhook signature not found in header")
+	ErrSignatureMismatch  = errors.New("Webhook signature does not match")
+	ErrSignatureTimestamp = errors.New("Webhook timestamp outside of tolerance window")
+)
+
+// SignWebhook returns the hex HMAC-SHA256 of "<unix timestamp>.<body>" under
+// secret, which is what senders put in SignatureHeader after "sha256=".
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature headers against body. Any of
+// secrets may have signed it, so a key can be rotated by accepting the old and
+// new one side by side. Requests stamped more than tolerance away from now are
+// rejected so a captured request can't be replayed later.
+func VerifyWebhookSignature(headers http.Header, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	rawSignature := headers.Get(SignatureHeader)
+	if rawSignature == "" {
+		return ErrNoSignature
+	}
+	hexSignature, ok := strings.CutPrefix(strings.TrimSpace(rawSignature), "sha256=")
+	if !ok {
+		return errors.New("Malformed webhook signature header")
+	}
+	signature, err := hex.DecodeString(hexSignature)
+	if err != nil {
+		return errors.New("Malformed webhook signature header")
+	}
+
+	unix, err := strconv.ParseInt(strings.TrimSpace(headers.Get(TimestampHeader)), 10, 64)
+	if err != nil {
+		return errors.New("Malformed webhook timestamp header")
+	}
+	timestamp := time.Unix(unix, 0)
+	if timestamp.Before(now.Add(-tolerance)) || timestamp.After(now.Add(tolerance)) {
+		return ErrSignatureTimestamp
+	}
+
+	for _, secret := range secrets {
+		expected, _ := hex.DecodeString(SignWebhook(secret, timestamp, body))
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
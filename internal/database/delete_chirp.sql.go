@@ -11,12 +11,55 @@ import (
 	"github.com/google/uuid"
 )
 
-const deleteChirpWithID = `-- name: DeleteChirpWithID :exec
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
 DELETE FROM chirps
-WHERE id = $1
+WHERE deleted_at IS NOT NULL
+    AND deleted_at < NOW() - make_interval(secs => $1::float8)
 `
 
-func (q *Queries) DeleteChirpWithID(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirpWithID, id)
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, retentionSeconds float64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, retentionSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirpWithID = `-- name: RestoreChirpWithID :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+    AND (body <> '' OR EXISTS (
+        SELECT 1 FROM chirps original
+        WHERE original.id = chirps.rechirp_of AND original.deleted_at IS NULL
+    ))
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, in_reply_to, like_count, rechirp_of
+`
+
+func (q *Queries) RestoreChirpWithID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirpWithID, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.InReplyTo,
+		&i.LikeCount,
+		&i.RechirpOf,
+	)
+	return i, err
+}
+
+const softDeleteChirpWithID = `-- name: SoftDeleteChirpWithID :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteChirpWithID(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirpWithID, id)
 	return err
 }
@@ -1,34 +0,0 @@
-// Code generated by sqlc. DO NOT EDIT.
-// versions:
-//   sqlc v1.30.0
-// source: get_user_by_refresh_token.sql
-
-package database
-
-import (
-	"context"
-)
-
-const getUserByRefreshToken = `-- name: GetUserByRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red
-FROM users
-LEFT JOIN refresh_tokens
-ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1 
-        AND refresh_tokens.revoked_at IS NULL 
-        AND refresh_tokens.expires_at > NOW()
-`
-
-func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByRefreshToken, token)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Email,
-		&i.HashedPassword,
-		&i.IsChirpyRed,
-	)
-	return i, err
-}
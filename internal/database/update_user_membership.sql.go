@@ -7,17 +7,26 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
-const updateUserMembershipByID = `-- name: UpdateUserMembershipByID :exec
+const updateUserMembershipByID = `-- name: UpdateUserMembershipByID :execrows
 UPDATE users
-SET is_chirpy_red = TRUE
+SET is_chirpy_red = $2, updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) UpdateUserMembershipByID(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, updateUserMembershipByID, id)
-	return err
+type UpdateUserMembershipByIDParams struct {
+	ID          uuid.UUID
+	IsChirpyRed sql.NullBool
+}
+
+func (q *Queries) UpdateUserMembershipByID(ctx context.Context, arg UpdateUserMembershipByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserMembershipByID, arg.ID, arg.IsChirpyRed)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
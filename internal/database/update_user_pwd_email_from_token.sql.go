@@ -7,15 +7,18 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 const updateUserPwdEmailByToken = `-- name: UpdateUserPwdEmailByToken :one
 UPDATE users
-SET email = $1, hashed_password = $2
+SET email = $1, hashed_password = $2,
+    -- A new address has to be verified again.
+    email_verified_at = CASE WHEN email = $1 THEN email_verified_at END
 WHERE id = $3  --ID get from checking JWT Token before parsing 
-RETURNING email
+RETURNING email, email_verified_at
 `
 
 type UpdateUserPwdEmailByTokenParams struct {
@@ -24,9 +27,14 @@ type UpdateUserPwdEmailByTokenParams struct {
 	ID             uuid.UUID
 }
 
-func (q *Queries) UpdateUserPwdEmailByToken(ctx context.Context, arg UpdateUserPwdEmailByTokenParams) (string, error) {
+type UpdateUserPwdEmailByTokenRow struct {
+	Email           string
+	EmailVerifiedAt sql.NullTime
+}
+
+func (q *Queries) UpdateUserPwdEmailByToken(ctx context.Context, arg UpdateUserPwdEmailByTokenParams) (UpdateUserPwdEmailByTokenRow, error) {
 	row := q.db.QueryRowContext(ctx, updateUserPwdEmailByToken, arg.Email, arg.HashedPassword, arg.ID)
-	var email string
-	err := row.Scan(&email)
-	return email, err
+	var i UpdateUserPwdEmailByTokenRow
+	err := row.Scan(&i.Email, &i.EmailVerifiedAt)
+	return i, err
 }
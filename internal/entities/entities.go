@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const maxHashtagLength = 100
+
+type Entities struct {
+	Hashtags []string
+	Mentions []string
+}
+
+// Parse pulls #hashtags and @mentions out of a chirp body. Hashtags are
+// lowercased; mentions are the lowercased email address following the @.
+// Each entity is reported once, in order of first appearance.
+func Parse(body string) Entities {
+	result := Entities{Hashtags: []string{}, Mentions: []string{}}
+	seenTags := map[string]struct{}{}
+	seenMentions := map[string]struct{}{}
+
+	for _, word := range strings.Fields(body) {
+		switch {
+		case strings.HasPrefix(word, "#"):
+			tag := NormalizeHashtag(word[1:])
+			if tag == "" {
+				continue
+			}
+			if _, ok := seenTags[tag]; !ok {
+				seenTags[tag] = struct{}{}
+				result.Hashtags = append(result.Hashtags, tag)
+			}
+		case strings.HasPrefix(word, "@"):
+			mention := normalizeMention(word[1:])
+			if mention == "" {
+				continue
+			}
+			if _, ok := seenMentions[mention]; !ok {
+				seenMentions[mention] = struct{}{}
+				result.Mentions = append(result.Mentions, mention)
+			}
+		}
+	}
+	return result
+}
+
+// NormalizeHashtag keeps the leading run of letters, digits and underscores
+// and lowercases it, so "#Go!" and "#go" are the same tag. It returns "" when
+// nothing usable is left.
+func NormalizeHashtag(raw string) string {
+	end := strings.IndexFunc(raw, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if end >= 0 {
+		raw = raw[:end]
+	}
+	if raw == "" || len(raw) > maxHashtagLength {
+		return ""
+	}
+	return strings.ToLower(raw)
+}
+
+func normalizeMention(raw string) string {
+	raw = strings.TrimRightFunc(raw, func(r rune) bool {
+		return unicode.IsPunct(r)
+	})
+	at := strings.Index(raw, "@")
+	if at <= 0 || at == len(raw)-1 {
+		return ""
+	}
+	return strings.ToLower(raw)
+}
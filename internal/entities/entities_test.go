@@ -0,0 +1,29 @@
+package entities
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseHashtags(t *testing.T) {
+	got := Parse("Loving #Go and #go, also #chirpy! but not # or #!").Hashtags
+	want := []string{"go", "chirpy"}
+	if !slices.Equal(got, want) {
+		t.Errorf("hashtags: got %v, want %v", got, want)
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	got := Parse("hey @Alice@Example.com, meet @bob@example.com. @nobody @").Mentions
+	want := []string{"alice@example.com", "bob@example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("mentions: got %v, want %v", got, want)
+	}
+}
+
+func TestParseEmptyBody(t *testing.T) {
+	got := Parse("")
+	if len(got.Hashtags) != 0 || len(got.Mentions) != 0 {
+		t.Errorf("expected no entities, got %+v", got)
+	}
+}
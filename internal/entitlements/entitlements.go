@@ -0,0 +1,86 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	TierFree = "free"
+	TierRed  = "red"
+)
+
+// Tier is the set of capabilities a membership level grants. A ChirpsPerHour
+// of 0 means posting is not rate limited. CanSchedulePosts and CanUploadMedia
+// are loaded like the rest but nothing checks them until scheduled posts and
+// media uploads exist.
+type Tier struct {
+	Name             string `json:"name"`
+	MaxChirpLength   int    `json:"max_chirp_length"`
+	ChirpsPerHour    int    `json:"chirps_per_hour"`
+	CanEditChirps    bool   `json:"can_edit_chirps"`
+	CanSchedulePosts bool   `json:"can_schedule_posts"`
+	CanUploadMedia   bool   `json:"can_upload_media"`
+	CanUseWebhooks   bool   `json:"can_use_webhooks"`
+}
+
+type Config struct {
+	Tiers map[string]Tier `json:"tiers"`
+}
+
+// Default is used when no entitlements file is configured.
+func Default() Config {
+	return Config{Tiers: map[string]Tier{
+		TierFree: {
+			Name:           TierFree,
+			MaxChirpLength: 140,
+			ChirpsPerHour:  30,
+		},
+		TierRed: {
+			Name:             TierRed,
+			MaxChirpLength:   280,
+			ChirpsPerHour:    300,
+			CanEditChirps:    true,
+			CanSchedulePosts: true,
+			CanUploadMedia:   true,
+			CanUseWebhooks:   true,
+		},
+	}}
+}
+
+// Load reads tier definitions from JSON shaped like
+//
+//	{"tiers": {"free": {...}, "red": {...}}}
+//
+// Both the free and red tiers must be defined.
+func Load(r io.Reader) (Config, error) {
+	cfg := Config{}
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("decoding entitlements: %w", err)
+	}
+
+	for _, name := range []string{TierFree, TierRed} {
+		tier, ok := cfg.Tiers[name]
+		if !ok {
+			return Config{}, fmt.Errorf("entitlements: tier %q is not defined", name)
+		}
+		if tier.MaxChirpLength <= 0 {
+			return Config{}, fmt.Errorf("entitlements: tier %q needs a positive max_chirp_length", name)
+		}
+		if tier.ChirpsPerHour < 0 {
+			return Config{}, fmt.Errorf("entitlements: tier %q has a negative chirps_per_hour", name)
+		}
+		tier.Name = name
+		cfg.Tiers[name] = tier
+	}
+	return cfg, nil
+}
+
+// ForMembership picks the tier for a user's membership flag.
+func (c Config) ForMembership(isChirpyRed bool) Tier {
+	if isChirpyRed {
+		return c.Tiers[TierRed]
+	}
+	return c.Tiers[TierFree]
+}
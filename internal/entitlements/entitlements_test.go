@@ -0,0 +1,50 @@
+package entitlements
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultTiers(t *testing.T) {
+	cfg := Default()
+	free := cfg.ForMembership(false)
+	red := cfg.ForMembership(true)
+
+	if free.Name != TierFree || red.Name != TierRed {
+		t.Fatalf("unexpected tier names: %q, %q", free.Name, red.Name)
+	}
+	if red.MaxChirpLength <= free.MaxChirpLength {
+		t.Errorf("red tier should allow longer chirps than free")
+	}
+	if free.CanEditChirps || !red.CanEditChirps {
+		t.Errorf("only red members should be able to edit chirps")
+	}
+	if free.CanUseWebhooks || !red.CanUseWebhooks {
+		t.Errorf("only red members should be able to subscribe to webhooks")
+	}
+	if free.CanSchedulePosts || !red.CanSchedulePosts || free.CanUploadMedia || !red.CanUploadMedia {
+		t.Errorf("only red members should be able to schedule posts and upload media")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load(strings.NewReader(`{"tiers": {
+		"free": {"max_chirp_length": 100, "chirps_per_hour": 5},
+		"red": {"max_chirp_length": 500, "chirps_per_hour": 0, "can_edit_chirps": true, "can_upload_media": true}
+	}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	red := cfg.ForMembership(true)
+	if red.Name != TierRed || red.MaxChirpLength != 500 || !red.CanEditChirps || !red.CanUploadMedia || red.CanSchedulePosts {
+		t.Errorf("red tier not loaded correctly: %+v", red)
+	}
+}
+
+func TestLoadRequiresBothTiers(t *testing.T) {
+	_, err := Load(strings.NewReader(`{"tiers": {"free": {"max_chirp_length": 100}}}`))
+	if err == nil {
+		t.Errorf("expected error when the red tier is missing")
+	}
+}
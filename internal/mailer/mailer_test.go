@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data, err := Format("Chirpy <no-reply@chirpy.test>", Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "line one\nline two",
+	}, date)
+	if err != nil {
+		t.Fatalf("Error formatting message: %v", err)
+	}
+
+	got := string(data)
+	for _, want := range []string{
+		"From: Chirpy <no-reply@chirpy.test>\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Hello\r\n",
+		"Date: Wed, 01 May 2024 12:00:00 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatted message missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	for _, msg := range []Message{
+		{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"},
+		{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com"},
+		{To: "not an address", Subject: "Hi"},
+	} {
+		if _, err := Format("no-reply@chirpy.test", msg, time.Now()); err == nil {
+			t.Errorf("expected an error formatting %+v", msg)
+		}
+	}
+}
+
+func TestWriterMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriterMailer(&buf, "no-reply@chirpy.test")
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "Hi", Body: "Hello"}); err != nil {
+			t.Fatalf("Error sending message: %v", err)
+		}
+	}
+
+	got := buf.String()
+	if !strings.Contains(got, "To: a@example.com") || !strings.Contains(got, "To: b@example.com") {
+		t.Errorf("expected both messages to be written, got:\n%s", got)
+	}
+}
@@ -0,0 +1,133 @@
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action is what happens to a chirp when a filter matches part of it.
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+const maskText = "****"
+
+func ParseAction(s string) (Action, error) {
+	switch action := Action(strings.ToLower(strings.TrimSpace(s))); action {
+	case ActionMask, ActionFlag, ActionReject:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown moderation action %q", s)
+	}
+}
+
+// Match is a span of the body, in byte offsets, that a filter objected to.
+type Match struct {
+	Filter string
+	Term   string
+	Action Action
+	Start  int
+	End    int
+}
+
+type Filter interface {
+	Name() string
+	Check(body string) []Match
+}
+
+// Result is the outcome of running a body through a Pipeline. Body has every
+// masked span replaced; Matches lists everything any filter reported.
+type Result struct {
+	Body    string
+	Matches []Match
+}
+
+// Flags returns the matches that should be queued for human review.
+func (r Result) Flags() []Match {
+	flags := []Match{}
+	for _, match := range r.Matches {
+		if match.Action == ActionFlag {
+			flags = append(flags, match)
+		}
+	}
+	return flags
+}
+
+type RejectedError struct {
+	Match Match
+}
+
+func (e *RejectedError) Error() string {
+	return "Chirp contains disallowed content"
+}
+
+type Pipeline struct {
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Moderate runs every filter over the original body. Any reject match fails
+// the whole chirp with a *RejectedError; mask matches are replaced with ****.
+// Several filters often catch the same word, so a span is only reported by
+// the first filter that matched it; a reject is never hidden by an earlier
+// mask of the same span.
+func (p *Pipeline) Moderate(body string) (Result, error) {
+	result := Result{Body: body, Matches: []Match{}}
+	type span struct{ start, end int }
+	seen := map[span]struct{}{}
+
+	for _, filter := range p.filters {
+		for _, match := range filter.Check(body) {
+			if match.Action == ActionReject {
+				return Result{}, &RejectedError{Match: match}
+			}
+			key := span{match.Start, match.End}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			result.Matches = append(result.Matches, match)
+		}
+	}
+
+	result.Body = mask(body, result.Matches)
+	return result, nil
+}
+
+func mask(body string, matches []Match) string {
+	spans := []Match{}
+	for _, match := range matches {
+		if match.Action == ActionMask {
+			spans = append(spans, match)
+		}
+	}
+	if len(spans) == 0 {
+		return body
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.Start < last {
+			// Overlaps a span that's already masked; extend it if needed.
+			if s.End > last {
+				last = s.End
+			}
+			continue
+		}
+		b.WriteString(body[last:s.Start])
+		b.WriteString(maskText)
+		last = s.End
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
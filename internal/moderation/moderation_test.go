@@ -0,0 +1,119 @@
+package moderation
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func defaultPipeline() *Pipeline {
+	list := NewWordList(map[string]Action{
+		"kerfuffle": ActionMask,
+		"sharbert":  ActionMask,
+		"fornax":    ActionMask,
+		"spamword":  ActionReject,
+		"iffy":      ActionFlag,
+	})
+	return NewPipeline(NewWordFilter(list), NewNormalizedWordFilter(list))
+}
+
+func TestMaskIgnoresCaseAndPunctuation(t *testing.T) {
+	result, err := defaultPipeline().Moderate("What a Kerfuffle! Oh, fornax.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "What a ****! Oh, ****."
+	if result.Body != want {
+		t.Errorf("got %q, want %q", result.Body, want)
+	}
+}
+
+func TestMaskCatchesLeetAndAccents(t *testing.T) {
+	result, err := defaultPipeline().Moderate("f0rn@x and shärbert and k.e.r.f.u.f.f.l.e")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "**** and **** and ****"
+	if result.Body != want {
+		t.Errorf("got %q, want %q", result.Body, want)
+	}
+}
+
+func TestRejectStopsChirp(t *testing.T) {
+	_, err := defaultPipeline().Moderate("buy SPAMWORD today")
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("expected RejectedError, got %v", err)
+	}
+	if rejected.Match.Term != "spamword" {
+		t.Errorf("rejected on %q, want spamword", rejected.Match.Term)
+	}
+}
+
+func TestRejectWinsOverEarlierMask(t *testing.T) {
+	pipeline := NewPipeline(
+		NewWordFilter(NewWordList(map[string]Action{"fornax": ActionMask})),
+		NewWordFilter(NewWordList(map[string]Action{"fornax": ActionReject})),
+	)
+	_, err := pipeline.Moderate("a fornax here")
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("expected RejectedError, got %v", err)
+	}
+}
+
+func TestFlagKeepsBody(t *testing.T) {
+	result, err := defaultPipeline().Moderate("this is iffy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Body != "this is iffy" {
+		t.Errorf("flagged body was modified: %q", result.Body)
+	}
+	if flags := result.Flags(); len(flags) != 1 || flags[0].Term != "iffy" {
+		t.Errorf("expected one flag for iffy, got %+v", flags)
+	}
+}
+
+func TestWordListReplace(t *testing.T) {
+	list := NewWordList(map[string]Action{"fornax": ActionMask})
+	pipeline := NewPipeline(NewWordFilter(list))
+	list.Replace(map[string]Action{"Zonk": ActionMask})
+
+	result, _ := pipeline.Moderate("fornax zonk")
+	if result.Body != "fornax ****" {
+		t.Errorf("got %q after replacing words", result.Body)
+	}
+}
+
+func TestRegexFilter(t *testing.T) {
+	pipeline := NewPipeline(NewRegexFilter([]RegexRule{
+		{Pattern: regexp.MustCompile(`\d{3}-\d{4}`), Action: ActionMask},
+	}))
+	result, _ := pipeline.Moderate("call 555-1234 now")
+	if result.Body != "call **** now" {
+		t.Errorf("got %q", result.Body)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(`
+# comment
+word mask Kerfuffle
+regex reject (?i)free\s+crypto
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules.Words["kerfuffle"] != ActionMask {
+		t.Errorf("word rule not loaded: %+v", rules.Words)
+	}
+	if len(rules.Regexes) != 1 || !rules.Regexes[0].Pattern.MatchString("FREE  crypto") {
+		t.Errorf("regex rule not loaded: %+v", rules.Regexes)
+	}
+
+	if _, err := LoadRules(strings.NewReader("word destroy x")); err == nil {
+		t.Errorf("expected error for unknown action")
+	}
+}
@@ -0,0 +1,96 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// leetReplacements maps common look-alike characters back to letters.
+var leetReplacements = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'|': 'i',
+}
+
+// diacriticFolds strips accents from the Latin letters people use to dodge
+// filters. It is deliberately small; anything else is left alone.
+var diacriticFolds = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a',
+	'ç': 'c', 'ć': 'c', 'č': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ė': 'e', 'ę': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ī': 'i', 'ı': 'i',
+	'ñ': 'n', 'ń': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o',
+	'ś': 's', 'š': 's', 'ß': 's',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ź': 'z', 'ż': 'z', 'ž': 'z',
+}
+
+// Normalize lowercases s, folds fullwidth and accented letters to ASCII,
+// undoes leet-speak substitutions and drops anything that isn't a letter, so
+// "F.0.R.N.Ä.X" becomes "fornax".
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'ａ' && r <= 'ｚ' {
+			r = r - 'ａ' + 'a'
+		}
+		if folded, ok := diacriticFolds[r]; ok {
+			r = folded
+		}
+		if replaced, ok := leetReplacements[r]; ok {
+			r = replaced
+		}
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isNormalizableRune(r rune) bool {
+	_, leet := leetReplacements[r]
+	return leet || isWordRune(r)
+}
+
+// NormalizedWordFilter checks the word list against each word after
+// Normalize, catching disguised spellings the plain WordFilter misses.
+type NormalizedWordFilter struct {
+	list *WordList
+}
+
+func NewNormalizedWordFilter(list *WordList) *NormalizedWordFilter {
+	return &NormalizedWordFilter{list: list}
+}
+
+func (f *NormalizedWordFilter) Name() string {
+	return "normalized"
+}
+
+func (f *NormalizedWordFilter) Check(body string) []Match {
+	matches := []Match{}
+	for _, tok := range tokens(body, isNormalizableRune) {
+		word := Normalize(tok.text)
+		if word == "" {
+			continue
+		}
+		if action, ok := f.list.lookup(word); ok {
+			matches = append(matches, Match{
+				Filter: f.Name(),
+				Term:   word,
+				Action: action,
+				Start:  tok.start,
+				End:    tok.end,
+			})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,40 @@
+package moderation
+
+import "regexp"
+
+type RegexRule struct {
+	Pattern *regexp.Regexp
+	Action  Action
+}
+
+// RegexFilter reports every match of each of its rules.
+type RegexFilter struct {
+	rules []RegexRule
+}
+
+func NewRegexFilter(rules []RegexRule) *RegexFilter {
+	return &RegexFilter{rules: rules}
+}
+
+func (f *RegexFilter) Name() string {
+	return "regex"
+}
+
+func (f *RegexFilter) Check(body string) []Match {
+	matches := []Match{}
+	for _, rule := range f.rules {
+		for _, loc := range rule.Pattern.FindAllStringIndex(body, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, Match{
+				Filter: f.Name(),
+				Term:   rule.Pattern.String(),
+				Action: rule.Action,
+				Start:  loc[0],
+				End:    loc[1],
+			})
+		}
+	}
+	return matches
+}
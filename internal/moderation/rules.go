@@ -0,0 +1,62 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+type Rules struct {
+	Words   map[string]Action
+	Regexes []RegexRule
+}
+
+// LoadRules reads a rules file with one rule per line:
+//
+//	word  mask    kerfuffle
+//	regex reject  (?i)free\s+crypto
+//
+// Blank lines and lines starting with # are ignored.
+func LoadRules(r io.Reader) (Rules, error) {
+	rules := Rules{Words: map[string]Action{}, Regexes: []RegexRule{}}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return Rules{}, fmt.Errorf("line %d: expected \"<kind> <action> <value>\"", lineNo)
+		}
+		action, err := ParseAction(fields[1])
+		if err != nil {
+			return Rules{}, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		// Regexes may contain spaces, so the value is the rest of the line.
+		value := strings.TrimSpace(line[len(fields[0]):])
+		value = strings.TrimSpace(value[len(fields[1]):])
+
+		switch fields[0] {
+		case "word":
+			rules.Words[strings.ToLower(value)] = action
+		case "regex":
+			pattern, err := regexp.Compile(value)
+			if err != nil {
+				return Rules{}, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			rules.Regexes = append(rules.Regexes, RegexRule{Pattern: pattern, Action: action})
+		default:
+			return Rules{}, fmt.Errorf("line %d: unknown rule kind %q", lineNo, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Rules{}, err
+	}
+	return rules, nil
+}
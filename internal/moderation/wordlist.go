@@ -0,0 +1,121 @@
+package moderation
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordList is the shared, runtime-editable set of banned words. Several
+// filters can read from the same list, so an admin edit applies to all of
+// them at once.
+type WordList struct {
+	mu    sync.RWMutex
+	words map[string]Action
+}
+
+func NewWordList(words map[string]Action) *WordList {
+	l := &WordList{}
+	l.Replace(words)
+	return l
+}
+
+// Replace swaps in a new set of words. Keys are lowercased.
+func (l *WordList) Replace(words map[string]Action) {
+	normalized := make(map[string]Action, len(words))
+	for word, action := range words {
+		normalized[strings.ToLower(word)] = action
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.words = normalized
+}
+
+func (l *WordList) lookup(word string) (Action, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	action, ok := l.words[word]
+	return action, ok
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokens splits body on whitespace and trims the surrounding characters that
+// keep reports true, so "fornax!" yields "fornax".
+func tokens(body string, keep func(rune) bool) []token {
+	result := []token{}
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		s, e := start, end
+		for s < e {
+			r, size := utf8.DecodeRuneInString(body[s:e])
+			if keep(r) {
+				break
+			}
+			s += size
+		}
+		for e > s {
+			r, size := utf8.DecodeLastRuneInString(body[s:e])
+			if keep(r) {
+				break
+			}
+			e -= size
+		}
+		if s < e {
+			result = append(result, token{text: body[s:e], start: s, end: e})
+		}
+		start = -1
+	}
+
+	for i, r := range body {
+		if unicode.IsSpace(r) {
+			flush(i)
+		} else if start < 0 {
+			start = i
+		}
+	}
+	flush(len(body))
+	return result
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// WordFilter matches whole words case-insensitively, ignoring punctuation
+// stuck to either end.
+type WordFilter struct {
+	list *WordList
+}
+
+func NewWordFilter(list *WordList) *WordFilter {
+	return &WordFilter{list: list}
+}
+
+func (f *WordFilter) Name() string {
+	return "wordlist"
+}
+
+func (f *WordFilter) Check(body string) []Match {
+	matches := []Match{}
+	for _, tok := range tokens(body, isWordRune) {
+		word := strings.ToLower(tok.text)
+		if action, ok := f.list.lookup(word); ok {
+			matches = append(matches, Match{
+				Filter: f.Name(),
+				Term:   word,
+				Action: action,
+				Start:  tok.start,
+				End:    tok.end,
+			})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,126 @@
+package textlen
+
+import (
+	"regexp"
+	"unicode"
+)
+
+// URLWeight is how many characters a link counts for, however long it is.
+const URLWeight = 23
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// Weighted returns the length of a chirp as users see it: every URL counts
+// as URLWeight and everything else is counted in grapheme clusters, so an
+// emoji or an accented letter is one character regardless of its encoding.
+func Weighted(s string) int {
+	length := 0
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(s, -1) {
+		length += Graphemes(s[last:loc[0]]) + URLWeight
+		last = loc[1]
+	}
+	return length + Graphemes(s[last:])
+}
+
+// Graphemes counts user-perceived characters following the main rules of
+// Unicode extended grapheme clusters (UAX #29): CR LF, combining marks,
+// variation selectors and emoji modifiers, ZWJ emoji sequences, regional
+// indicator flag pairs and Hangul syllable sequences.
+func Graphemes(s string) int {
+	count := 0
+	var prev rune
+	started := false
+	riRun := 0
+	for _, r := range s {
+		if !started || !continuesCluster(prev, r, riRun) {
+			count++
+		}
+		if isRegionalIndicator(r) {
+			riRun++
+		} else {
+			riRun = 0
+		}
+		prev = r
+		started = true
+	}
+	return count
+}
+
+func continuesCluster(prev, r rune, riRun int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case prev == '\r' || prev == '\n' || r == '\r' || r == '\n':
+		return false
+	case isExtend(r):
+		return true
+	case prev == zwj && isPictographic(r):
+		return true
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		// Flags are pairs; the run count includes prev.
+		return riRun%2 == 1
+	}
+	return hangulContinues(hangulTypeOf(prev), hangulTypeOf(r))
+}
+
+const zwj = '\u200d'
+
+func isExtend(r rune) bool {
+	return r == zwj ||
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // emoji skin tone modifiers
+		(r >= 0xE0020 && r <= 0xE007F) // tag characters in subdivision flags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isPictographic(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) ||
+		(r >= 0x2600 && r <= 0x27BF) ||
+		(r >= 0x2300 && r <= 0x23FF) ||
+		(r >= 0x2B00 && r <= 0x2BFF) ||
+		r == 0x00A9 || r == 0x00AE || r == 0x203C || r == 0x2049 || r == 0x2122
+}
+
+type hangulType int
+
+const (
+	hangulNone hangulType = iota
+	hangulL
+	hangulV
+	hangulT
+	hangulLV
+	hangulLVT
+)
+
+func hangulTypeOf(r rune) hangulType {
+	switch {
+	case (r >= 0x1100 && r <= 0x115F) || (r >= 0xA960 && r <= 0xA97C):
+		return hangulL
+	case (r >= 0x1160 && r <= 0x11A7) || (r >= 0xD7B0 && r <= 0xD7C6):
+		return hangulV
+	case (r >= 0x11A8 && r <= 0x11FF) || (r >= 0xD7CB && r <= 0xD7FB):
+		return hangulT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return hangulLV
+		}
+		return hangulLVT
+	}
+	return hangulNone
+}
+
+func hangulContinues(prev, next hangulType) bool {
+	switch prev {
+	case hangulL:
+		return next == hangulL || next == hangulV || next == hangulLV || next == hangulLVT
+	case hangulLV, hangulV:
+		return next == hangulV || next == hangulT
+	case hangulLVT, hangulT:
+		return next == hangulT
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package textlen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphemes(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"ascii", "hello", 5},
+		{"empty", "", 0},
+		{"combining accent", "e\u0301", 1},
+		{"emoji", "😀😀", 2},
+		{"skin tone", "👍🏽", 1},
+		{"zwj family", "👨\u200d👩\u200d👧", 1},
+		{"variation selector", "❤\ufe0f", 1},
+		{"flags", "🇯🇵🇫🇷", 2},
+		{"odd regional indicators", "🇯🇵🇫", 2},
+		{"crlf", "a\r\nb", 3},
+		{"hangul jamo", "\u1100\u1161\u11a8", 1},
+		{"hangul syllables", "한국", 2},
+	}
+	for _, c := range cases {
+		if got := Graphemes(c.in); got != c.want {
+			t.Errorf("%s: Graphemes(%q) = %d, want %d", c.name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestFiftyEmojiFitInAChirp(t *testing.T) {
+	body := strings.Repeat("😀", 50)
+	if len(body) <= 140 {
+		t.Fatalf("test body should exceed 140 bytes, got %d", len(body))
+	}
+	if got := Weighted(body); got != 50 {
+		t.Errorf("Weighted = %d, want 50", got)
+	}
+}
+
+func TestWeightedURLs(t *testing.T) {
+	body := "read https://example.com/a/very/long/path?with=query&and=more now"
+	want := len("read ") + URLWeight + len(" now")
+	if got := Weighted(body); got != want {
+		t.Errorf("Weighted = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"example.com/m/internal/database"
+	"example.com/m/internal/moderation"
+	"github.com/google/uuid"
+)
+
+// loadModerationRules reads the optional rules file named by
+// MODERATION_RULES_FILE. Its words form a base list that the runtime-managed
+// words in the database are layered on top of.
+func loadModerationRules() moderation.Rules {
+	path := os.Getenv("MODERATION_RULES_FILE")
+	if path == "" {
+		return moderation.Rules{Words: map[string]moderation.Action{}}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening moderation rules file: %s", err)
+	}
+	defer f.Close()
+
+	rules, err := moderation.LoadRules(f)
+	if err != nil {
+		log.Fatalf("Error reading moderation rules file: %s", err)
+	}
+	return rules
+}
+
+// reloadModerationWords rebuilds the shared word list from the rules file and
+// the moderation_words table. Database entries win when both define a word.
+func (cfg *apiConfig) reloadModerationWords(ctx context.Context) error {
+	dbWords, err := cfg.db.GetModerationWords(ctx)
+	if err != nil {
+		return err
+	}
+
+	words := make(map[string]moderation.Action, len(cfg.moderationRules.Words)+len(dbWords))
+	for word, action := range cfg.moderationRules.Words {
+		words[word] = action
+	}
+	for _, dbWord := range dbWords {
+		action, err := moderation.ParseAction(dbWord.Action)
+		if err != nil {
+			log.Printf("Skipping moderation word %q: %s", dbWord.Word, err)
+			continue
+		}
+		words[dbWord.Word] = action
+	}
+
+	cfg.moderationWords.Replace(words)
+	return nil
+}
+
+// recordModerationFlags queues every flag match on a chirp for review.
+func recordModerationFlags(ctx context.Context, q *database.Queries, chirpID uuid.UUID, result moderation.Result) error {
+	for _, flag := range result.Flags() {
+		err := q.CreateModerationFlag(ctx, database.CreateModerationFlagParams{
+			ChirpID: chirpID,
+			Filter:  flag.Filter,
+			Term:    flag.Term,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
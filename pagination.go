@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// chirpCursor marks the last chirp of a page. It is handed to clients as an
+// opaque base64 string and decoded back into a (created_at, id) keyset bound,
+// or (rank, id) for relevance-ordered search results. Rank is only set on
+// search cursors, so search can turn away a cursor from another listing.
+type chirpCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Rank      *float32  `json:"r,omitempty"`
+}
+
+type pageParams struct {
+	limit  int32
+	desc   bool
+	cursor *chirpCursor
+}
+
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func parsePageParams(r *http.Request) (pageParams, error) {
+	query := r.URL.Query()
+	params := pageParams{limit: defaultPageLimit}
+
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 {
+			return pageParams{}, errors.New("limit must be a positive integer")
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		params.limit = int32(limit)
+	}
+
+	// Every listing defaults to oldest first, as GET /api/chirps always has.
+	switch query.Get("sort") {
+	case "", "asc":
+	case "desc":
+		params.desc = true
+	default:
+		return pageParams{}, errors.New("sort must be either asc or desc")
+	}
+
+	if rawCursor := query.Get("cursor"); rawCursor != "" {
+		cursor, err := decodeCursor(rawCursor)
+		if err != nil {
+			return pageParams{}, err
+		}
+		params.cursor = &cursor
+	}
+
+	return params, nil
+}
+
+// fetchLimit asks for one extra row so we know whether another page exists
+// without a separate COUNT query.
+func (p pageParams) fetchLimit() int32 {
+	return p.limit + 1
+}
+
+func (p pageParams) cursorArgs() (sql.NullTime, uuid.NullUUID) {
+	if p.cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: p.cursor.CreatedAt, Valid: true},
+		uuid.NullUUID{UUID: p.cursor.ID, Valid: true}
+}
+
+// parseAuthorFilter reads the optional author_id query parameter shared by
+// every chirp listing endpoint.
+func parseAuthorFilter(r *http.Request) (uuid.NullUUID, error) {
+	author := r.URL.Query().Get("author_id")
+	if len(author) == 0 {
+		return uuid.NullUUID{}, nil
+	}
+	authorID, err := uuid.Parse(author)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: authorID, Valid: true}, nil
+}
+
+// splitPage drops the look-ahead row requested by fetchLimit and reports
+// whether there is another page after this one.
+func splitPage[T any](rows []T, p pageParams) ([]T, bool) {
+	if len(rows) > int(p.limit) {
+		return rows[:p.limit], true
+	}
+	return rows, false
+}
+
+func encodeCursor(c chirpCursor) string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (chirpCursor, error) {
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return chirpCursor{}, errors.New("Malformed cursor")
+	}
+	c := chirpCursor{}
+	if err := json.Unmarshal(dat, &c); err != nil || c.ID == uuid.Nil {
+		return chirpCursor{}, errors.New("Malformed cursor")
+	}
+	return c, nil
+}
+
+func newChirpPage(dbChirps []database.Chirp, p pageParams) chirpPage {
+	page := chirpPage{Chirps: []Chirp{}}
+	dbChirps, hasMore := splitPage(dbChirps, p)
+
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromDB(dbChirp))
+	}
+
+	if hasMore {
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page
+}
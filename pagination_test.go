@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestParsePageParams(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		wantLimit int32
+		wantDesc  bool
+		wantErr   bool
+	}{
+		{"defaults", "", defaultPageLimit, false, false},
+		{"explicit limit", "limit=5", 5, false, false},
+		{"limit clamped", "limit=1000", maxPageLimit, false, false},
+		{"limit at max", "limit=100", maxPageLimit, false, false},
+		{"zero limit", "limit=0", 0, false, true},
+		{"negative limit", "limit=-3", 0, false, true},
+		{"non-numeric limit", "limit=ten", 0, false, true},
+		{"asc", "sort=asc", defaultPageLimit, false, false},
+		{"desc", "sort=desc", defaultPageLimit, true, false},
+		{"unknown sort", "sort=sideways", 0, false, true},
+		{"malformed cursor", "cursor=not-base64!", 0, false, true},
+		{"cursor without id", "cursor=" + encodeCursor(chirpCursor{CreatedAt: time.Now()}), 0, false, true},
+		{"cursor that isn't json", "cursor=bm90IGpzb24", 0, false, true},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/api/chirps?"+c.query, nil)
+		got, err := parsePageParams(r)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if got.limit != c.wantLimit || got.desc != c.wantDesc {
+			t.Errorf("%s: got limit %d desc %v, want %d %v", c.name, got.limit, got.desc, c.wantLimit, c.wantDesc)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	rank := float32(0.5)
+	cases := []chirpCursor{
+		{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()},
+		{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), ID: uuid.New(), Rank: &rank},
+	}
+	for _, want := range cases {
+		got, err := decodeCursor(encodeCursor(want))
+		if err != nil {
+			t.Fatalf("decodeCursor: %v", err)
+		}
+		if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		if (got.Rank == nil) != (want.Rank == nil) || (got.Rank != nil && *got.Rank != *want.Rank) {
+			t.Errorf("rank: got %v, want %v", got.Rank, want.Rank)
+		}
+	}
+}
+
+func TestSplitPage(t *testing.T) {
+	p := pageParams{limit: 3}
+	cases := []struct {
+		rows     int
+		wantLen  int
+		wantMore bool
+	}{
+		{0, 0, false},
+		{2, 2, false},
+		{3, 3, false},
+		{4, 3, true},
+	}
+	for _, c := range cases {
+		got, more := splitPage(make([]int, c.rows), p)
+		if len(got) != c.wantLen || more != c.wantMore {
+			t.Errorf("%d rows: got %d more=%v, want %d more=%v", c.rows, len(got), more, c.wantLen, c.wantMore)
+		}
+	}
+	if p.fetchLimit() != 4 {
+		t.Errorf("fetchLimit = %d, want one look-ahead row", p.fetchLimit())
+	}
+}
+
+func TestNewChirpPageNextCursor(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows := []database.Chirp{}
+	for i := range 3 {
+		rows = append(rows, database.Chirp{ID: uuid.New(), CreatedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+
+	page := newChirpPage(rows, pageParams{limit: 2})
+	if len(page.Chirps) != 2 {
+		t.Fatalf("got %d chirps, want 2", len(page.Chirps))
+	}
+	cursor, err := decodeCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("next_cursor doesn't decode: %v", err)
+	}
+	if cursor.ID != rows[1].ID || !cursor.CreatedAt.Equal(rows[1].CreatedAt) {
+		t.Errorf("next_cursor should point at the last chirp on the page, got %+v", cursor)
+	}
+
+	last := newChirpPage(rows, pageParams{limit: 3})
+	if last.NextCursor != "" {
+		t.Errorf("last page should have no next_cursor, got %q", last.NextCursor)
+	}
+}
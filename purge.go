@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	defaultChirpRetention = 30 * 24 * time.Hour
+	purgeInterval         = time.Hour
+)
+
+// purgeDeletedChirps periodically hard-deletes chirps that have been
+// soft-deleted for longer than the retention window. Until then an admin can
+// still bring them back through /admin/chirps/{chirpID}/restore.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		// The cutoff is worked out by the database, whose clock also wrote
+		// deleted_at.
+		purged, err := cfg.db.PurgeDeletedChirps(ctx, retention.Seconds())
+		if err != nil {
+			log.Printf("Error purging deleted chirps: %s", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted chirps older than %s", purged, retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
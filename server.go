@@ -1,23 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"time"
 
+	"example.com/m/internal/auth"
 	"example.com/m/internal/database"
+	"example.com/m/internal/entitlements"
+	"example.com/m/internal/mailer"
+	"example.com/m/internal/moderation"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	dbConn         *sql.DB
 	db             *database.Queries
 	platform       string
-	key            string
+	key            *auth.Keyring
+	authUsers      *auth.UserCache
+	mailer         mailer.Mailer
 	api            string
+	polkaSecrets   []string
+	polkaTolerance time.Duration
+	trending       trendingCache
+
+	entitlements entitlements.Config
+
+	moderation      *moderation.Pipeline
+	moderationWords *moderation.WordList
+	moderationRules moderation.Rules
 }
 
 func main() {
@@ -39,42 +57,139 @@ func main() {
 		log.Fatalf("Error opening database: %s", err)
 	}
 	dbQueries := database.New(dbConn)
-	ss := os.Getenv("SECRET")
-	if ss == "" {
-		log.Fatal("SECRET must be set")
-	}
+	keys := loadSigningKeys()
 	polka := os.Getenv("POLKA_KEY")
-	if polka == "" {
-		log.Fatal("POLKA_KEY must be set")
+	// During a rotation both the new and the outgoing secret are accepted.
+	polkaSecrets := []string{}
+	for _, name := range []string{"POLKA_WEBHOOK_SECRET", "POLKA_WEBHOOK_SECRET_PREVIOUS"} {
+		if secret := os.Getenv(name); secret != "" {
+			polkaSecrets = append(polkaSecrets, secret)
+		}
+	}
+	if polka == "" && len(polkaSecrets) == 0 {
+		log.Fatal("POLKA_KEY or POLKA_WEBHOOK_SECRET must be set")
+	}
+	polkaTolerance := defaultWebhookTolerance
+	if rawTolerance := os.Getenv("POLKA_WEBHOOK_TOLERANCE"); rawTolerance != "" {
+		polkaTolerance, err = time.ParseDuration(rawTolerance)
+		if err != nil {
+			log.Fatalf("POLKA_WEBHOOK_TOLERANCE must be a valid duration: %s", err)
+		}
+	}
+	retention := defaultChirpRetention
+	if rawRetention := os.Getenv("CHIRP_RETENTION"); rawRetention != "" {
+		retention, err = time.ParseDuration(rawRetention)
+		if err != nil {
+			log.Fatalf("CHIRP_RETENTION must be a valid duration: %s", err)
+		}
+	}
+
+	trendingRefresh := defaultTrendingRefresh
+	if rawRefresh := os.Getenv("TRENDING_REFRESH_INTERVAL"); rawRefresh != "" {
+		trendingRefresh, err = time.ParseDuration(rawRefresh)
+		if err != nil || trendingRefresh <= 0 {
+			log.Fatalf("TRENDING_REFRESH_INTERVAL must be a positive duration: %v", err)
+		}
 	}
 
+	moderationRules := loadModerationRules()
+	moderationWords := moderation.NewWordList(moderationRules.Words)
+
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
+		dbConn:         dbConn,
 		db:             dbQueries,
 		platform:       platform,
-		key:            ss,
+		key:            keys,
+		mailer:         loadMailer(),
 		api:            polka,
+		polkaSecrets:   polkaSecrets,
+		polkaTolerance: polkaTolerance,
+
+		entitlements: loadEntitlements(),
+
+		moderation: moderation.NewPipeline(
+			moderation.NewWordFilter(moderationWords),
+			moderation.NewNormalizedWordFilter(moderationWords),
+			moderation.NewRegexFilter(moderationRules.Regexes),
+		),
+		moderationWords: moderationWords,
+		moderationRules: moderationRules,
 	}
 
+	if err := apiCfg.reloadModerationWords(context.Background()); err != nil {
+		log.Fatalf("Error loading moderation words: %s", err)
+	}
+
+	go apiCfg.purgeDeletedChirps(context.Background(), retention)
+	go apiCfg.refreshTrending(context.Background(), trendingRefresh)
+	go apiCfg.deliverWebhooks(context.Background(), webhookDeliveryInterval)
+
+	apiCfg.authUsers = auth.NewUserCache(apiCfg.lookupAuthUser, authUserCacheTTL)
+	authn := auth.NewAuthenticator(keys, apiCfg.authUsers.Get)
+	required := func(h http.HandlerFunc) http.Handler { return authn.Required(h) }
+	optional := func(h http.HandlerFunc) http.Handler { return authn.Optional(h) }
+	moderator := func(h http.HandlerFunc) http.Handler { return authn.RequireRole(auth.RoleModerator, h) }
+	admin := func(h http.HandlerFunc) http.Handler { return authn.RequireRole(auth.RoleAdmin, h) }
+
 	mux := http.NewServeMux()
 	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
 	mux.Handle("/app/", fsHandler)
 
 	mux.HandleFunc("GET /api/healthz", handlerReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handlerJWKS)
 
 	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
+	mux.HandleFunc("POST /api/users/verify", apiCfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", apiCfg.handlerResendVerification)
 	mux.HandleFunc("POST /api/login", apiCfg.handlerUsersLogin)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetChirps)
-	mux.HandleFunc("GET /api/chirps/{param1}", apiCfg.handlerGetChirpByID)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerChirpsDelete)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handlerForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handlerResetPassword)
+	mux.Handle("POST /api/chirps", required(apiCfg.handlerChirpsCreate))
+	mux.Handle("GET /api/chirps", optional(apiCfg.handlerGetChirps))
+	mux.Handle("GET /api/chirps/search", optional(apiCfg.handlerSearchChirps))
+	mux.Handle("POST /api/chirps/validate", optional(apiCfg.handlerValidateChirp))
+	mux.Handle("GET /api/chirps/{param1}", optional(apiCfg.handlerGetChirpByID))
+	mux.Handle("PUT /api/chirps/{chirpID}", required(apiCfg.handlerChirpsUpdate))
+	mux.Handle("DELETE /api/chirps/{chirpID}", required(apiCfg.handlerChirpsDelete))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handlerGetChirpRevisions)
+	mux.Handle("GET /api/chirps/{chirpID}/thread", optional(apiCfg.handlerGetChirpThread))
+	mux.Handle("POST /api/chirps/{chirpID}/like", required(apiCfg.handlerLikeChirp))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", required(apiCfg.handlerUnlikeChirp))
+	mux.Handle("POST /api/chirps/{chirpID}/rechirp", required(apiCfg.handlerRechirp))
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefreshJWT)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevokeRefreshToken)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUserLoginUpdate)
+	mux.Handle("GET /api/sessions", required(apiCfg.handlerGetSessions))
+	mux.Handle("DELETE /api/sessions", required(apiCfg.handlerRevokeAllSessions))
+	mux.Handle("DELETE /api/sessions/{sessionID}", required(apiCfg.handlerRevokeSession))
+	mux.Handle("PUT /api/users", required(apiCfg.handlerUserLoginUpdate))
+	mux.Handle("POST /api/users/{userID}/follow", required(apiCfg.handlerFollowUser))
+	mux.Handle("DELETE /api/users/{userID}/follow", required(apiCfg.handlerUnfollowUser))
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handlerGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handlerGetFollowing)
+	mux.Handle("GET /api/users/me/mentions", required(apiCfg.handlerGetMyMentions))
+	mux.Handle("GET /api/timeline", required(apiCfg.handlerGetTimeline))
+	mux.Handle("GET /api/tags/{tag}/chirps", optional(apiCfg.handlerGetTagChirps))
+	mux.HandleFunc("GET /api/trending", apiCfg.handlerGetTrending)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerUpdateMembership)
+	mux.Handle("POST /api/webhooks", required(apiCfg.handlerWebhooksCreate))
+	mux.Handle("GET /api/webhooks", required(apiCfg.handlerWebhooksList))
+	mux.Handle("DELETE /api/webhooks/{webhookID}", required(apiCfg.handlerWebhooksDelete))
+	mux.Handle("GET /api/webhooks/{webhookID}/deliveries", required(apiCfg.handlerWebhookDeliveries))
+	mux.Handle("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/retry", required(apiCfg.handlerRetryWebhookDelivery))
 
+	// Reset deletes every user, admins included, so it stays gated on
+	// PLATFORM=dev alone; requiring an admin would lock the instance out after
+	// the first reset.
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	mux.Handle("GET /admin/metrics", admin(apiCfg.handlerMetrics))
+	mux.Handle("PUT /admin/users/{userID}/role", admin(apiCfg.handlerSetUserRole))
+	mux.Handle("POST /admin/chirps/{chirpID}/restore", moderator(apiCfg.handlerRestoreChirp))
+	mux.Handle("GET /admin/moderation/words", moderator(apiCfg.handlerGetModerationWords))
+	mux.Handle("PUT /admin/moderation/words", moderator(apiCfg.handlerPutModerationWord))
+	mux.Handle("DELETE /admin/moderation/words/{word}", moderator(apiCfg.handlerDeleteModerationWord))
+	mux.Handle("GET /admin/moderation/flags", moderator(apiCfg.handlerGetModerationFlags))
+	mux.Handle("GET /admin/webhooks", admin(apiCfg.handlerGetWebhookEvents))
 
 	srv := &http.Server{
 		Addr:    ":" + port,
@@ -0,0 +1,3 @@
+-- name: CountChirpsByUserSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND created_at > NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8);
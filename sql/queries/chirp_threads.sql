@@ -0,0 +1,33 @@
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+    SELECT parent.id, parent.in_reply_to, 1
+    FROM chirps child
+    JOIN chirps parent ON parent.id = child.in_reply_to
+    WHERE child.id = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1
+    FROM ancestors
+    JOIN chirps parent ON parent.id = ancestors.in_reply_to
+    WHERE ancestors.depth < sqlc.arg('max_depth')::int
+)
+SELECT sqlc.embed(chirps), ancestors.depth::int AS depth
+FROM ancestors
+JOIN chirps ON chirps.id = ancestors.id
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants (id, depth) AS (
+    SELECT reply.id, 1
+    FROM chirps reply
+    WHERE reply.in_reply_to = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT reply.id, descendants.depth + 1
+    FROM descendants
+    JOIN chirps reply ON reply.in_reply_to = descendants.id
+    WHERE descendants.depth < sqlc.arg('max_depth')::int
+)
+SELECT sqlc.embed(chirps), descendants.depth::int AS depth
+FROM descendants
+JOIN chirps ON chirps.id = descendants.id
+ORDER BY descendants.depth, chirps.created_at, chirps.id
+LIMIT sqlc.arg('max_replies');
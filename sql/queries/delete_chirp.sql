@@ -1,3 +1,19 @@
--- name: DeleteChirpWithID :exec
+-- name: SoftDeleteChirpWithID :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreChirpWithID :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+    AND (body <> '' OR EXISTS (
+        SELECT 1 FROM chirps original
+        WHERE original.id = chirps.rechirp_of AND original.deleted_at IS NULL
+    ))
+RETURNING *;
+
+-- name: PurgeDeletedChirps :execrows
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE deleted_at IS NOT NULL
+    AND deleted_at < NOW() - make_interval(secs => sqlc.arg('retention_seconds')::float8);
@@ -0,0 +1,20 @@
+-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (id, created_at, user_id, used_at)
+VALUES (
+    gen_random_uuid(), NOW(), $1, NULL
+)
+RETURNING *;
+
+-- name: CountEmailVerificationsSince :one
+SELECT COUNT(*) FROM email_verifications
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: GetEmailVerificationForUpdate :one
+SELECT * FROM email_verifications
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UseEmailVerifications :exec
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,42 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowersPageAsc :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, follower_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, follower_id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetFollowersPageDesc :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, follower_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetFollowingPageAsc :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, followee_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, followee_id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetFollowingPageDesc :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, followee_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('page_limit');
@@ -1,12 +1,21 @@
--- name: GetChirps :many
+-- name: GetChirpByID :one
 SELECT * FROM chirps
-ORDER BY created_at;
+WHERE id = $1 AND deleted_at IS NULL;
 
--- name: GetChirpByID :one
+-- name: GetChirpsPageAsc :many
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE deleted_at IS NULL
+    AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
 
--- name: GetChirpsByUserID :many
+-- name: GetChirpsPageDesc :many
 SELECT * FROM chirps
-WHERE user_id = $1
-ORDER BY created_at;
\ No newline at end of file
+WHERE deleted_at IS NULL
+    AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
@@ -1,3 +1,8 @@
 -- name: GetUserByEmail :one
 SELECT * FROM users
-WHERE email = $1;
\ No newline at end of file
+WHERE email = $1;
+
+-- name: GetUserByEmailForUpdate :one
+SELECT * FROM users
+WHERE email = $1
+FOR UPDATE;
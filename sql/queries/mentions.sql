@@ -0,0 +1,34 @@
+-- name: GetUserIDsByEmails :many
+SELECT id FROM users
+WHERE lower(email) = ANY(sqlc.arg('emails')::text[]);
+
+-- name: AddMention :exec
+INSERT INTO mentions (chirp_id, user_id, created_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ClearChirpMentions :exec
+DELETE FROM mentions
+WHERE chirp_id = $1;
+
+-- name: GetMentionChirpsPageAsc :many
+SELECT chirps.* FROM chirps
+JOIN mentions ON mentions.chirp_id = chirps.id
+WHERE mentions.user_id = sqlc.arg('user_id')
+    AND chirps.deleted_at IS NULL
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetMentionChirpsPageDesc :many
+SELECT chirps.* FROM chirps
+JOIN mentions ON mentions.chirp_id = chirps.id
+WHERE mentions.user_id = sqlc.arg('user_id')
+    AND chirps.deleted_at IS NULL
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
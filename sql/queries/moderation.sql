@@ -0,0 +1,27 @@
+-- name: GetModerationWords :many
+SELECT * FROM moderation_words
+ORDER BY word;
+
+-- name: UpsertModerationWord :one
+INSERT INTO moderation_words (word, action, created_at, updated_at)
+VALUES (
+    $1, $2, NOW(), NOW()
+)
+ON CONFLICT (word) DO UPDATE
+SET action = EXCLUDED.action, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteModerationWord :execrows
+DELETE FROM moderation_words
+WHERE word = $1;
+
+-- name: CreateModerationFlag :exec
+INSERT INTO moderation_flags (id, created_at, chirp_id, filter, term)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3
+);
+
+-- name: GetModerationFlags :many
+SELECT * FROM moderation_flags
+ORDER BY created_at DESC
+LIMIT $1;
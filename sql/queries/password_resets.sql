@@ -0,0 +1,20 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (token_hash, created_at, user_id, expires_at, used_at)
+VALUES (
+    $1, NOW(), $2, NOW() + INTERVAL '1 hour', NULL
+)
+RETURNING *;
+
+-- name: CountPasswordResetsSince :one
+SELECT COUNT(*) FROM password_resets
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: GetPasswordResetForUpdate :one
+SELECT * FROM password_resets
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: UsePasswordResets :exec
+UPDATE password_resets
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
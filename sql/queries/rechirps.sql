@@ -0,0 +1,21 @@
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: SoftDeletePlainRechirpsOf :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE rechirp_of = $1 AND body = '' AND deleted_at IS NULL;
+
+-- name: RestorePlainRechirpsOf :exec
+UPDATE chirps
+SET deleted_at = NULL
+WHERE rechirp_of = $1 AND body = ''
+    AND deleted_at = (SELECT original.deleted_at FROM chirps original WHERE original.id = $1);
@@ -1,6 +1,17 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id)
 VALUES (
-    $1, NOW(), NOW(), $2, NOW() + INTERVAL '60 days', NULL
+    $1, NOW(), NOW(), $2, NOW() + INTERVAL '60 days', NULL, $3
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetRefreshTokenForUpdate :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: HasRefreshTokenSuccessor :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE family_id = $1 AND created_at > $2
+);
@@ -0,0 +1,10 @@
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps), ts_rank(to_tsvector('english', chirps.body), query)::real AS rank
+FROM chirps, websearch_to_tsquery('english', sqlc.arg('query')::text) AS query
+WHERE to_tsvector('english', chirps.body) @@ query
+    AND chirps.deleted_at IS NULL
+    AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+    AND (sqlc.narg('cursor_rank')::real IS NULL
+        OR (ts_rank(to_tsvector('english', chirps.body), query)::real, chirps.id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid))
+ORDER BY rank DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,22 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, last_used_at, user_id, user_agent, ip, last_user_agent, last_ip)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $2, $3
+)
+RETURNING *;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW(), last_user_agent = $2, last_ip = $3
+WHERE id = $1;
+
+-- name: GetActiveSessionsByUser :many
+SELECT * FROM sessions
+WHERE user_id = $1
+    AND EXISTS (
+        SELECT 1 FROM refresh_tokens
+        WHERE refresh_tokens.family_id = sessions.id
+            AND refresh_tokens.revoked_at IS NULL
+            AND refresh_tokens.expires_at > NOW()
+    )
+ORDER BY last_used_at DESC;
@@ -0,0 +1,32 @@
+-- name: IncrementHashtagUsage :exec
+INSERT INTO hashtag_usage_buckets (hashtag_id, bucket_start, uses)
+VALUES (
+    $1, date_trunc('minute', NOW()), 1
+)
+ON CONFLICT (hashtag_id, bucket_start) DO UPDATE
+SET uses = hashtag_usage_buckets.uses + 1;
+
+-- name: AdjustChirpHashtagUsage :exec
+INSERT INTO hashtag_usage_buckets (hashtag_id, bucket_start, uses)
+SELECT hashtag_id, date_trunc('minute', created_at), sqlc.arg('delta')::int
+FROM chirp_hashtags
+WHERE chirp_id = sqlc.arg('chirp_id')
+ON CONFLICT (hashtag_id, bucket_start) DO UPDATE
+SET uses = hashtag_usage_buckets.uses + EXCLUDED.uses;
+
+-- name: GetTrendingHashtags :many
+SELECT hashtags.tag,
+    SUM(hashtag_usage_buckets.uses)::bigint AS uses,
+    SUM(hashtag_usage_buckets.uses * exp(
+        -ln(2) * extract(epoch FROM NOW() - hashtag_usage_buckets.bucket_start) / sqlc.arg('half_life_seconds')::float8
+    ))::float8 AS score
+FROM hashtag_usage_buckets
+JOIN hashtags ON hashtags.id = hashtag_usage_buckets.hashtag_id
+WHERE hashtag_usage_buckets.bucket_start > NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+GROUP BY hashtags.tag
+ORDER BY score DESC, hashtags.tag
+LIMIT sqlc.arg('tag_limit');
+
+-- name: DeleteHashtagUsageBefore :exec
+DELETE FROM hashtag_usage_buckets
+WHERE bucket_start < NOW() - make_interval(secs => sqlc.arg('max_age_seconds')::float8);
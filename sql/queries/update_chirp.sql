@@ -0,0 +1,10 @@
+-- name: GetChirpByIDForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -1,4 +1,4 @@
--- name: UpdateUserMembershipByID :exec
+-- name: UpdateUserMembershipByID :execrows
 UPDATE users
-SET is_chirpy_red = TRUE
-WHERE id = $1;
\ No newline at end of file
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1;
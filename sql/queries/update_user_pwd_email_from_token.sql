@@ -1,5 +1,7 @@
 -- name: UpdateUserPwdEmailByToken :one
 UPDATE users
-SET email = $1, hashed_password = $2
+SET email = $1, hashed_password = $2,
+    -- A new address has to be verified again.
+    email_verified_at = CASE WHEN email = $1 THEN email_verified_at END
 WHERE id = $3  --ID get from checking JWT Token before parsing 
-RETURNING email;
\ No newline at end of file
+RETURNING email, email_verified_at;
\ No newline at end of file
@@ -3,4 +3,34 @@ INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1
+RETURNING token_version;
+
+-- name: UpdateUserRole :execrows
+UPDATE users
+SET role = $2, token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1;
+
+-- name: LockAdmins :many
+SELECT id FROM users
+WHERE role = 'admin'
+FOR UPDATE;
+
+-- name: VerifyUserEmail :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1;
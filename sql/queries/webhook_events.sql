@@ -0,0 +1,11 @@
+-- name: CreateWebhookEvent :execrows
+INSERT INTO webhook_events (id, received_at, event, user_id, status, payload)
+VALUES (
+    $1, NOW(), $2, $3, $4, $5
+)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetWebhookEvents :many
+SELECT * FROM webhook_events
+ORDER BY received_at DESC
+LIMIT $1;
@@ -0,0 +1,69 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, created_at, updated_at, user_id, url, secret, events)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetWebhookSubscriptionsByUser :many
+SELECT * FROM webhook_subscriptions
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetWebhookSubscriptionByID :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1 AND user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (id, created_at, updated_at, subscription_id, event, payload, status, attempts, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), id, sqlc.arg('event')::text, sqlc.arg('payload')::jsonb, 'pending', 0, NOW()
+FROM webhook_subscriptions
+WHERE user_id = sqlc.arg('owner_id') AND sqlc.arg('event')::text = ANY(events);
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg('lease_seconds')::float8)
+FROM webhook_subscriptions
+WHERE webhook_subscriptions.id = webhook_deliveries.subscription_id
+AND webhook_deliveries.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg('max_deliveries')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING webhook_deliveries.id, webhook_deliveries.event, webhook_deliveries.payload, webhook_deliveries.attempts, webhook_subscriptions.url, webhook_subscriptions.secret;
+
+-- name: UpdateWebhookDeliveryStatus :exec
+UPDATE webhook_deliveries
+SET status = sqlc.arg('status'),
+    attempts = attempts + 1,
+    next_attempt_at = NOW() + make_interval(secs => sqlc.arg('retry_in_seconds')::float8),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: RetryWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND subscription_id = $2 AND status = 'dead';
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, created_at, delivery_id, status_code, error)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3
+);
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = ANY(sqlc.arg('delivery_ids')::uuid[])
+ORDER BY created_at;
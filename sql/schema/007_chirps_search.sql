@@ -0,0 +1,7 @@
+-- +goose Up
+-- Search vectors are computed from the body when searching rather than
+-- stored, so chirp queries don't read them back on every list.
+CREATE INDEX chirps_body_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_body_search_idx;
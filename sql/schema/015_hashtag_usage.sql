@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE hashtag_usage_buckets (
+    hashtag_id UUID NOT NULL REFERENCES hashtags(id) ON DELETE CASCADE,
+    bucket_start TIMESTAMP NOT NULL,
+    uses INTEGER NOT NULL,
+    PRIMARY KEY (hashtag_id, bucket_start)
+);
+
+CREATE INDEX hashtag_usage_buckets_bucket_start_idx ON hashtag_usage_buckets (bucket_start);
+
+-- +goose Down
+DROP TABLE hashtag_usage_buckets;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE webhook_events (
+    id TEXT PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL,
+    event TEXT NOT NULL,
+    user_id UUID,
+    status TEXT NOT NULL CHECK (status IN ('processed', 'ignored')),
+    payload JSONB NOT NULL
+);
+
+CREATE INDEX webhook_events_received_at_idx ON webhook_events (received_at);
+
+-- +goose Down
+DROP TABLE webhook_events;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+-- Existing tokens keep working: clients still hold the raw value, and from
+-- now on it is looked up by its hash.
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+
+-- +goose Down
+-- Hashes can't be turned back into tokens, so everyone has to sign in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
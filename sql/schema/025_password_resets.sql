@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE password_resets (
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id, created_at);
+
+-- +goose Down
+DROP TABLE password_resets;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Mentions look users up by lower(email).
+CREATE INDEX users_email_lower_idx ON users (lower(email));
+
+-- +goose Down
+DROP INDEX users_email_lower_idx;
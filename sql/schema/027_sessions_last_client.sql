@@ -0,0 +1,13 @@
+-- +goose Up
+-- user_agent and ip keep the client that signed in; these follow whichever
+-- client refreshed the session last.
+ALTER TABLE sessions
+ADD COLUMN last_user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_ip TEXT NOT NULL DEFAULT '';
+
+UPDATE sessions SET last_user_agent = user_agent, last_ip = ip;
+
+-- +goose Down
+ALTER TABLE sessions
+DROP COLUMN last_user_agent,
+DROP COLUMN last_ip;
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"example.com/m/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+var errChirpRateLimited = errors.New("You are posting too fast, try again later")
+
+// loadEntitlements reads tier definitions from the JSON file named by
+// ENTITLEMENTS_FILE, or falls back to the built-in free and red tiers.
+func loadEntitlements() entitlements.Config {
+	path := os.Getenv("ENTITLEMENTS_FILE")
+	if path == "" {
+		return entitlements.Default()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening entitlements file: %s", err)
+	}
+	defer f.Close()
+
+	tiers, err := entitlements.Load(f)
+	if err != nil {
+		log.Fatalf("Error reading entitlements file: %s", err)
+	}
+	return tiers
+}
+
+// userTier is the set of capabilities the caller's membership grants.
+// Anonymous callers get the free tier.
+func (cfg *apiConfig) userTier(ctx context.Context) entitlements.Tier {
+	user, _ := auth.UserFromContext(ctx)
+	return cfg.entitlements.ForMembership(user.IsChirpyRed)
+}
+
+// lookupAuthUser loads the membership, role and token version of the user an
+// access token belongs to for auth.Authenticator.
+func (cfg *apiConfig) lookupAuthUser(ctx context.Context, userID uuid.UUID) (auth.User, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.User{}, auth.ErrUserNotFound
+	}
+	if err != nil {
+		return auth.User{}, err
+	}
+	return auth.User{
+		ID:           user.ID,
+		IsChirpyRed:  user.IsChirpyRed.Bool,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+	}, nil
+}
+
+// checkChirpRate returns errChirpRateLimited once the user has posted their
+// tier's allowance of chirps in the past hour.
+func (cfg *apiConfig) checkChirpRate(ctx context.Context, userID uuid.UUID, tier entitlements.Tier) error {
+	if tier.ChirpsPerHour == 0 {
+		return nil
+	}
+
+	recent, err := cfg.db.CountChirpsByUserSince(ctx, database.CountChirpsByUserSinceParams{
+		UserID:        userID,
+		WindowSeconds: time.Hour.Seconds(),
+	})
+	if err != nil {
+		return err
+	}
+	if recent >= int64(tier.ChirpsPerHour) {
+		return errChirpRateLimited
+	}
+	return nil
+}
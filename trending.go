@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"example.com/m/internal/database"
+)
+
+const (
+	defaultTrendingRefresh = time.Minute
+	trendingTagLimit       = 20
+)
+
+// trendingWindows are the sliding windows served by GET /api/trending. Each
+// decays with a half-life of a quarter of its window, so recent uses dominate.
+var trendingWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Uses  int64   `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+type trendingSnapshot struct {
+	Tags       []TrendingTag
+	ComputedAt time.Time
+}
+
+// trendingCache holds the latest ranking for each window. Scores are computed
+// from per-minute usage buckets kept up to date as chirps are written,
+// deleted and restored, so a refresh never touches the chirps table.
+type trendingCache struct {
+	mu        sync.RWMutex
+	snapshots map[string]trendingSnapshot
+}
+
+func (c *trendingCache) get(window string) (trendingSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snapshot, ok := c.snapshots[window]
+	return snapshot, ok
+}
+
+func (c *trendingCache) set(window string, snapshot trendingSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshots == nil {
+		c.snapshots = map[string]trendingSnapshot{}
+	}
+	c.snapshots[window] = snapshot
+}
+
+func (cfg *apiConfig) refreshTrending(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for window, length := range trendingWindows {
+			rows, err := cfg.db.GetTrendingHashtags(ctx, database.GetTrendingHashtagsParams{
+				HalfLifeSeconds: (length / 4).Seconds(),
+				WindowSeconds:   length.Seconds(),
+				TagLimit:        trendingTagLimit,
+			})
+			if err != nil {
+				log.Printf("Error computing trending hashtags for %s: %s", window, err)
+				continue
+			}
+
+			snapshot := trendingSnapshot{Tags: []TrendingTag{}, ComputedAt: time.Now().UTC()}
+			for _, row := range rows {
+				snapshot.Tags = append(snapshot.Tags, TrendingTag{
+					Tag:   row.Tag,
+					Uses:  row.Uses,
+					Score: row.Score,
+				})
+			}
+			cfg.trending.set(window, snapshot)
+		}
+
+		// Buckets older than the widest window can never count again.
+		err := cfg.db.DeleteHashtagUsageBefore(ctx, trendingWindows["7d"].Seconds())
+		if err != nil {
+			log.Printf("Error pruning hashtag usage: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"example.com/m/internal/auth"
+	"example.com/m/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookChirpCreated = "chirp.created"
+	webhookChirpDeleted = "chirp.deleted"
+	webhookUserUpgraded = "user.upgraded"
+)
+
+var webhookEventTypes = []string{webhookChirpCreated, webhookChirpDeleted, webhookUserUpgraded}
+
+const (
+	webhookDeliveryInterval = 5 * time.Second
+	webhookDeliveryBatch    = 20
+	webhookDeliveryTimeout  = 10 * time.Second
+	// A claimed batch must finish well inside the lease, or another pass
+	// would pick the same deliveries up again.
+	webhookDeliveryLease = 5 * time.Minute
+	webhookMaxAttempts   = 8
+	webhookBaseBackoff   = 30 * time.Second
+	webhookMaxBackoff    = 6 * time.Hour
+)
+
+type webhookEnvelope struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// enqueueWebhook queues event for the subscriptions owner has set up, as long
+// as their membership still allows webhooks. Callers pass their transaction's
+// queries so the event is only sent if the change that caused it commits.
+func (cfg *apiConfig) enqueueWebhook(ctx context.Context, q *database.Queries, owner uuid.UUID, event string, data any) error {
+	user, err := q.GetUserByID(ctx, owner)
+	if err != nil {
+		return err
+	}
+	entitled := cfg.entitlements.ForMembership(user.IsChirpyRed.Bool).CanUseWebhooks ||
+		user.Role == auth.RoleAdmin
+	if !entitled {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhookEnvelope{
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		Event:   event,
+		Payload: payload,
+		OwnerID: owner,
+	})
+}
+
+// deliverWebhooks periodically posts due deliveries to their subscribers.
+// Failed deliveries are retried with exponential backoff until they run out of
+// attempts, at which point they are parked as dead until retried by hand.
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context, interval time.Duration) {
+	client := newWebhookClient()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deliveries, err := cfg.db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+			LeaseSeconds:  webhookDeliveryLease.Seconds(),
+			MaxDeliveries: webhookDeliveryBatch,
+		})
+		if err != nil {
+			log.Printf("Error claiming webhook deliveries: %s", err)
+		}
+		for _, delivery := range deliveries {
+			cfg.attemptWebhookDelivery(ctx, client, delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) attemptWebhookDelivery(ctx context.Context, client *http.Client, delivery database.ClaimWebhookDeliveriesRow) {
+	statusCode, err := postWebhook(ctx, client, delivery)
+
+	attempt := database.CreateWebhookDeliveryAttemptParams{DeliveryID: delivery.ID}
+	if statusCode != 0 {
+		attempt.StatusCode = sql.NullInt32{Int32: int32(statusCode), Valid: true}
+	}
+	if err != nil {
+		attempt.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+	if err := cfg.db.CreateWebhookDeliveryAttempt(ctx, attempt); err != nil {
+		log.Printf("Error logging webhook delivery %s: %s", delivery.ID, err)
+	}
+
+	update := database.UpdateWebhookDeliveryStatusParams{ID: delivery.ID, Status: "delivered"}
+	if err != nil {
+		attempts := delivery.Attempts + 1
+		if attempts >= webhookMaxAttempts {
+			update.Status = "dead"
+		} else {
+			update.Status = "pending"
+			update.RetryInSeconds = webhookBackoff(attempts).Seconds()
+		}
+	}
+	if err := cfg.db.UpdateWebhookDeliveryStatus(ctx, update); err != nil {
+		log.Printf("Error updating webhook delivery %s: %s", delivery.ID, err)
+	}
+}
+
+// webhookBackoff is how long to wait after the given number of failed
+// attempts: 30s, 1m, 2m, ... capped at webhookMaxBackoff.
+func webhookBackoff(attempts int32) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := int32(1); i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
+
+// postWebhook sends one delivery, signed with the subscription's secret the
+// same way Polka signs the webhooks it sends us. Any non-2xx response counts
+// as a failure.
+func postWebhook(ctx context.Context, client *http.Client, delivery database.ClaimWebhookDeliveriesRow) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks")
+	req.Header.Set("X-Chirpy-Event", delivery.Event)
+	req.Header.Set("X-Chirpy-Delivery", delivery.ID.String())
+	req.Header.Set("X-Chirpy-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("X-Chirpy-Signature", "sha256="+auth.SignWebhook(delivery.Secret, now, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+var errWebhookAddressBlocked = errors.New("Webhook url must point to a public address")
+
+// webhookAddressAllowed rejects addresses on this host or its private
+// networks, so a subscription can't be used to reach internal services.
+func webhookAddressAllowed(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// checkWebhookHost resolves host and fails if any of its addresses is one
+// webhookAddressAllowed rejects.
+func checkWebhookHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !webhookAddressAllowed(addr.IP) {
+			return errWebhookAddressBlocked
+		}
+	}
+	return nil
+}
+
+// newWebhookClient checks every address it connects to, since DNS can change
+// after a subscription is created, and doesn't follow redirects, which could
+// point anywhere.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !webhookAddressAllowed(ip) {
+				return errWebhookAddressBlocked
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookDeliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+type chirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type userUpgradedEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+}